@@ -0,0 +1,35 @@
+package admin
+
+type MissingFieldContext struct {
+	Field string
+	Count uint64
+}
+
+type ParserStatusContext struct {
+	Name             string
+	Pages            uint64
+	EmptyPages       uint64
+	Results          uint64
+	SkippedResults   uint64
+	Errors           uint64
+	PaginationErrors uint64
+	MissingFields    []MissingFieldContext
+	LastError        string
+	LastErrorAt      string
+	EmptyRate        string
+	Broken           bool
+}
+
+type CacheStatusContext struct {
+	Hits      uint64
+	Misses    uint64
+	Coalesced uint64
+	Entries   int
+	HitRate   string
+}
+
+type StatusPageContext struct {
+	Parsers       []ParserStatusContext
+	BrokenParsers []string
+	Cache         CacheStatusContext
+}
@@ -0,0 +1,39 @@
+package admin
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+
+	"sitelook/app/search"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Only these vars are served, the default ones include the command line and
+// with it the image proxy key and proxy credentials
+var publishedVars = []string{"parsers", "cache"}
+
+func init() {
+	expvar.Publish("parsers", expvar.Func(func() interface{} {
+		return search.GetParserStats()
+	}))
+	expvar.Publish("cache", expvar.Func(func() interface{} {
+		return search.GetCacheStats()
+	}))
+}
+
+func StatusRoute(c *gin.Context) {
+	context := createStatusPageContext(search.GetParserStats(), search.GetCacheStats())
+	c.HTML(http.StatusOK, "admin-status-page", context)
+}
+
+// VarsRoute serves parser and cache counters in the expvar json format
+func VarsRoute(c *gin.Context) {
+	vars := make(map[string]json.RawMessage, len(publishedVars))
+	for _, name := range publishedVars {
+		vars[name] = json.RawMessage(expvar.Get(name).String())
+	}
+
+	c.JSON(http.StatusOK, vars)
+}
@@ -0,0 +1,41 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestVarsRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/admin/vars", VarsRoute)
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/vars", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", recorder.Code, http.StatusOK)
+	}
+
+	vars := map[string]json.RawMessage{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &vars); err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// the command line holds the image proxy key and proxy credentials
+	if want := []string{"cache", "parsers"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got vars %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,75 @@
+package admin
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"sitelook/app/search"
+)
+
+func formatRate(part uint64, total uint64) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", float64(part)/float64(total)*100)
+}
+
+func createParserStatusContext(stats search.ParserStats) ParserStatusContext {
+	missingFields := make([]MissingFieldContext, 0, len(stats.MissingFields))
+	for field, count := range stats.MissingFields {
+		missingFields = append(missingFields, MissingFieldContext{Field: field, Count: count})
+	}
+
+	sort.Slice(missingFields, func(i, j int) bool {
+		return missingFields[i].Field < missingFields[j].Field
+	})
+
+	lastErrorAt := ""
+	if !stats.LastErrorAt.IsZero() {
+		lastErrorAt = stats.LastErrorAt.Format(time.RFC3339)
+	}
+
+	return ParserStatusContext{
+		Name:             stats.Name,
+		Pages:            stats.Pages,
+		EmptyPages:       stats.EmptyPages,
+		Results:          stats.Results,
+		SkippedResults:   stats.SkippedResults,
+		Errors:           stats.Errors,
+		PaginationErrors: stats.PaginationErrors,
+		MissingFields:    missingFields,
+		LastError:        stats.LastError,
+		LastErrorAt:      lastErrorAt,
+		EmptyRate:        fmt.Sprintf("%.0f%%", stats.EmptyRate*100),
+		Broken:           stats.Broken,
+	}
+}
+
+func createCacheStatusContext(stats search.CacheStats) CacheStatusContext {
+	return CacheStatusContext{
+		Hits:      stats.Hits,
+		Misses:    stats.Misses,
+		Coalesced: stats.Coalesced,
+		Entries:   stats.Entries,
+		HitRate:   formatRate(stats.Hits, stats.Hits+stats.Misses+stats.Coalesced),
+	}
+}
+
+func createStatusPageContext(parserStats []search.ParserStats, cacheStats search.CacheStats) StatusPageContext {
+	parsers := make([]ParserStatusContext, len(parserStats))
+	brokenParsers := []string{}
+
+	for i, stats := range parserStats {
+		parsers[i] = createParserStatusContext(stats)
+		if stats.Broken {
+			brokenParsers = append(brokenParsers, stats.Name)
+		}
+	}
+
+	return StatusPageContext{
+		Parsers:       parsers,
+		BrokenParsers: brokenParsers,
+		Cache:         createCacheStatusContext(cacheStats),
+	}
+}
@@ -0,0 +1,184 @@
+package assets
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const RoutePath = "/static"
+
+const (
+	templatesDir = "templates"
+	staticDir    = "static"
+)
+
+// Hashed names change with the content, so they can be cached for a year.
+// Unhashed names are still served for links from outside of the templates.
+const (
+	hashedCacheControl   = "public, max-age=31536000, immutable"
+	unhashedCacheControl = "no-cache"
+)
+
+const hashLength = 10
+
+type staticFile struct {
+	name string
+	data []byte
+	hash string
+}
+
+var staticFiles = map[string]*staticFile{}
+var hashedNames = map[string]string{}
+var templates *template.Template
+
+// Load reads templates and static files from files, which holds the
+// `templates` and `static` directories. Files in the same directories of
+// themeDir replace the ones with the same name or are added to them.
+func Load(files fs.FS, themeDir string) error {
+	layers := []fs.FS{files}
+	if len(themeDir) > 0 {
+		info, err := os.Stat(themeDir)
+		if err != nil {
+			return fmt.Errorf("theme directory: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("theme directory %s isn't a directory", themeDir)
+		}
+		layers = append(layers, os.DirFS(themeDir))
+	}
+
+	templateFiles := map[string][]byte{}
+	staticData := map[string][]byte{}
+
+	for _, layer := range layers {
+		if err := readTree(layer, templatesDir, templateFiles); err != nil {
+			return err
+		}
+		if err := readTree(layer, staticDir, staticData); err != nil {
+			return err
+		}
+	}
+
+	loadedFiles := map[string]*staticFile{}
+	hashed := map[string]string{}
+
+	for name, data := range staticData {
+		sum := sha256.Sum256(data)
+		file := &staticFile{name: name, data: data, hash: hex.EncodeToString(sum[:])[:hashLength]}
+		loadedFiles[name] = file
+		hashed[hashedName(name, file.hash)] = name
+	}
+
+	staticFiles = loadedFiles
+	hashedNames = hashed
+
+	parsedTemplates, err := parseTemplates(templateFiles)
+	if err != nil {
+		return err
+	}
+
+	templates = parsedTemplates
+	return nil
+}
+
+// readTree reads the files under dir into files by their path relative to
+// dir, a missing dir is skipped
+func readTree(fsys fs.FS, dir string, files map[string][]byte) error {
+	err := fs.WalkDir(fsys, dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			return nil
+		}
+
+		data, err := fs.ReadFile(fsys, filePath)
+		if err != nil {
+			return err
+		}
+
+		files[strings.TrimPrefix(filePath, dir+"/")] = data
+		return nil
+	})
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+func parseTemplates(templateFiles map[string][]byte) (*template.Template, error) {
+	names := make([]string, 0, len(templateFiles))
+	for name := range templateFiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	root := template.New("").Funcs(template.FuncMap{"asset": Url})
+
+	for _, name := range names {
+		if _, err := root.New(name).Parse(string(templateFiles[name])); err != nil {
+			return nil, err
+		}
+	}
+
+	return root, nil
+}
+
+// hashedName inserts the hash before the extension, `css/home-page.css`
+// becomes `css/home-page.<hash>.css`
+func hashedName(name string, hash string) string {
+	extension := path.Ext(name)
+	return strings.TrimSuffix(name, extension) + "." + hash + extension
+}
+
+func Templates() *template.Template {
+	return templates
+}
+
+// Url returns the hashed url of a static file, used in templates as
+// `{{ asset "css/search-page.css" }}`
+func Url(name string) string {
+	file, ok := staticFiles[name]
+	if !ok {
+		return RoutePath + "/" + name
+	}
+	return RoutePath + "/" + hashedName(name, file.hash)
+}
+
+func StaticRoute(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("filepath"), "/")
+	cacheControl := hashedCacheControl
+
+	originalName, isHashed := hashedNames[name]
+	if isHashed {
+		name = originalName
+	} else {
+		cacheControl = unhashedCacheControl
+	}
+
+	file, ok := staticFiles[name]
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	c.Header("Cache-Control", cacheControl)
+	c.Header("ETag", `"`+file.hash+`"`)
+	http.ServeContent(c.Writer, c.Request, file.name, time.Time{}, bytes.NewReader(file.data))
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"flag"
+	"strings"
+	"time"
+)
+
+// Config holds the server options. Every option is a command line flag, which
+// can also be set in the config file or with a SITELOOK_* environment variable.
+type Config struct {
+	ConfigFile          string
+	Listen              string
+	ReadHeaderTimeout   time.Duration
+	ReadTimeout         time.Duration
+	WriteTimeout        time.Duration
+	IdleTimeout         time.Duration
+	ShutdownTimeout     time.Duration
+	ShutdownDelay       time.Duration
+	BaseUrl             string
+	ThemeDir            string
+	DefaultBackend      string
+	GoogleUrl           string
+	SearxngUrl          string
+	MetasearchBackends  []string
+	MetasearchTimeout   time.Duration
+	FallbackBackends    []string
+	BreakerThreshold    int
+	BreakerCooldown     time.Duration
+	UpstreamTimeout     time.Duration
+	MaxResponseSize     int64
+	UserAgent           string
+	Fingerprints        string
+	FingerprintRotation string
+	FingerprintSession  time.Duration
+	Proxies             []string
+	ProxyRotation       string
+	ProxyIsolation      bool
+	Language            string
+	CacheSize           int
+	CacheTtl            time.Duration
+	DiskCacheDir        string
+	DiskCacheSize       int64
+	DiskCacheTtl        time.Duration
+	RecordDir           string
+	ReplayDir           string
+	SelectorProfile     string
+	Admin               bool
+	Metrics             bool
+	AdminAddr           string
+	BreakageThreshold   float64
+	BreakageWindow      int
+	ParserDumpDir       string
+	Completions         bool
+	ImageProxy          bool
+	ImageProxyKey       string
+	LogLevel            string
+	LogFormat           string
+	LogQueries          bool
+	Debug               bool
+
+	// SITELOOK_* environment variables not matching any option, e.g. the
+	// ones Kubernetes sets for a service named sitelook
+	IgnoredVariables []string
+}
+
+const defaultUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36"
+
+// listValue is a comma separated list flag, setting it replaces the list
+type listValue struct {
+	items *[]string
+}
+
+func (v listValue) String() string {
+	if v.items == nil {
+		return ""
+	}
+	return strings.Join(*v.items, ",")
+}
+
+func (v listValue) Set(list string) error {
+	*v.items = splitList(list)
+	return nil
+}
+
+func splitList(list string) []string {
+	items := []string{}
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func newFlagSet(name string, config *Config) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+
+	flags.StringVar(&config.ConfigFile, "config", "", "yaml or json file setting options by their flag names, flags and environment variables take precedence")
+	flags.StringVar(&config.Listen, "listen", ":8080", "address the server listens on")
+	flags.DurationVar(&config.ReadHeaderTimeout, "read-header-timeout", 5*time.Second, "how long clients may take to send request headers")
+	flags.DurationVar(&config.ReadTimeout, "read-timeout", 15*time.Second, "how long clients may take to send a whole request")
+	flags.DurationVar(&config.WriteTimeout, "write-timeout", time.Minute, "how long a response may take from the end of the request headers, longer than -upstream-timeout")
+	flags.DurationVar(&config.IdleTimeout, "idle-timeout", 2*time.Minute, "how long idle keep-alive connections are kept open")
+	flags.DurationVar(&config.ShutdownTimeout, "shutdown-timeout", 15*time.Second, "how long active requests may finish on SIGINT or SIGTERM before their upstream requests are cancelled")
+	flags.DurationVar(&config.ShutdownDelay, "shutdown-delay", 0, "how long new requests are still accepted after /readyz starts failing on shutdown")
+	flags.StringVar(&config.BaseUrl, "base-url", "", "public url of the instance used in the OpenSearch description, derived from requests when empty")
+	flags.StringVar(&config.ThemeDir, "theme-dir", "", "directory with templates and static subdirectories whose files replace the built-in ones")
+	flags.StringVar(&config.DefaultBackend, "backend", "google", "search backend used when a request doesn't specify one (`meta` for metasearch)")
+	flags.StringVar(&config.GoogleUrl, "google-url", "https://google.com", "url of the Google instance searches are sent to")
+	flags.StringVar(&config.SearxngUrl, "searxng-url", "", "url of a SearXNG instance, enables the `searxng` backend")
+
+	config.MetasearchBackends = []string{"google", "duckduckgo", "bing"}
+	flags.Var(listValue{&config.MetasearchBackends}, "metasearch-backends", "comma separated backends queried in the metasearch mode")
+	flags.DurationVar(&config.MetasearchTimeout, "metasearch-timeout", 5*time.Second, "how long the metasearch waits for backends")
+
+	config.FallbackBackends = []string{}
+	flags.Var(listValue{&config.FallbackBackends}, "fallback-backends", "comma separated backends tried in order when the requested one fails")
+	flags.IntVar(&config.BreakerThreshold, "breaker-threshold", 3, "consecutive failures after which a backend is skipped")
+	flags.DurationVar(&config.BreakerCooldown, "breaker-cooldown", 5*time.Minute, "how long a failing backend is skipped")
+	flags.DurationVar(&config.UpstreamTimeout, "upstream-timeout", 10*time.Second, "how long requests to backends may take")
+	flags.Int64Var(&config.MaxResponseSize, "max-response-size", 8<<20, "maximum size of backend responses in bytes, larger ones fail")
+	flags.StringVar(&config.UserAgent, "user-agent", defaultUserAgent, "User-Agent header of requests to image servers, backend requests use the fingerprint profiles")
+	flags.StringVar(&config.Fingerprints, "fingerprints", "", "yaml or json file replacing the built-in header profiles of backend requests, reloaded on SIGHUP")
+	flags.StringVar(&config.FingerprintRotation, "fingerprint-rotation", "request", "how often the header profile changes: request (a random one per request) or session")
+	flags.DurationVar(&config.FingerprintSession, "fingerprint-session", 30*time.Minute, "how long a header profile is kept in the session rotation, every proxy has its own session")
+
+	config.Proxies = []string{}
+	flags.Var(listValue{&config.Proxies}, "proxy", "comma separated http, https or socks5 proxy urls requests to backends and image proxy requests are rotated through")
+	flags.StringVar(&config.ProxyRotation, "proxy-rotation", "round-robin", "how the proxy of a backend request is picked: round-robin or health (skips failing proxies)")
+	flags.BoolVar(&config.ProxyIsolation, "proxy-isolation", false, "send random credentials to socks5 proxies with every request, which gives each request its own Tor circuit")
+	flags.StringVar(&config.Language, "language", "", "interface language used when a request doesn't specify one (e.g. `en` or `pt-BR`)")
+
+	flags.IntVar(&config.CacheSize, "cache-size", 1000, "number of result pages kept in memory, 0 disables the cache")
+	flags.DurationVar(&config.CacheTtl, "cache-ttl", 10*time.Minute, "how long result pages are kept in memory")
+	flags.StringVar(&config.DiskCacheDir, "disk-cache-dir", "", "directory storing upstream responses across restarts, disabled when empty")
+	flags.Int64Var(&config.DiskCacheSize, "disk-cache-size", 256<<20, "maximum size of responses in the disk cache in bytes")
+	flags.DurationVar(&config.DiskCacheTtl, "disk-cache-ttl", 24*time.Hour, "how long responses are served from the disk cache")
+	flags.StringVar(&config.RecordDir, "record", "", "directory every upstream response is recorded into")
+	flags.StringVar(&config.ReplayDir, "replay", "", "directory of recorded responses served instead of querying backends")
+	flags.StringVar(&config.SelectorProfile, "selector-profile", "", "yaml or json file overriding selectors of the Google parser, reloaded on SIGHUP")
+
+	flags.BoolVar(&config.Admin, "admin", false, "serve the status page at /admin/status and counters at /admin/vars")
+	flags.BoolVar(&config.Metrics, "metrics", false, "serve Prometheus metrics at /metrics")
+	flags.StringVar(&config.AdminAddr, "admin-addr", "", "separate address (e.g. `localhost:9090`) serving the admin routes and metrics instead of the main one")
+	flags.Float64Var(&config.BreakageThreshold, "breakage-threshold", 0.5, "share of recent pages without results after which a parser is reported as broken")
+	flags.IntVar(&config.BreakageWindow, "breakage-window", 20, "number of recent pages the breakage threshold applies to")
+	flags.StringVar(&config.ParserDumpDir, "parser-dump-dir", "", "directory saving pages parsers found no results in, disabled when empty")
+
+	flags.BoolVar(&config.Completions, "completions", false, "suggest completions of the search term in the search input, costs an additional backend request per search")
+	flags.BoolVar(&config.ImageProxy, "image-proxy", true, "serve result thumbnails through the instance instead of the backends' image servers")
+	flags.StringVar(&config.ImageProxyKey, "image-proxy-key", "", "key signing image proxy urls, a random key is generated when empty")
+
+	flags.StringVar(&config.LogLevel, "log-level", "info", "minimum level of logged messages: debug, info, warn or error")
+	flags.StringVar(&config.LogFormat, "log-format", "text", "format of log messages: text or json")
+	flags.BoolVar(&config.LogQueries, "log-queries", false, "include search terms and other query parameters in request logs")
+	flags.BoolVar(&config.Debug, "debug", false, "log at the debug level including whole parsed pages, which contain search terms")
+
+	return flags
+}
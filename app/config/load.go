@@ -0,0 +1,155 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	envPrefix     = "SITELOOK_"
+	configFileEnv = envPrefix + "CONFIG"
+)
+
+// Load reads the config file, the environment and the command line arguments
+// (without the program name), later sources override earlier ones. The
+// config is validated, every invalid option is reported in the error.
+func Load(name string, args []string) (Config, error) {
+	config := Config{}
+	flags := newFlagSet(name, &config)
+
+	// the command line is parsed once to find the config file and once more
+	// at the end, so flags take precedence over the other sources
+	if err := parseArgs(flags, args); err != nil {
+		return config, err
+	}
+
+	configFile := config.ConfigFile
+	if len(configFile) == 0 {
+		configFile = os.Getenv(configFileEnv)
+	}
+
+	if len(configFile) > 0 {
+		if err := applyConfigFile(flags, configFile); err != nil {
+			return config, err
+		}
+	}
+
+	ignoredVariables, err := applyEnvironment(flags, os.Environ())
+	if err != nil {
+		return config, err
+	}
+
+	if err := parseArgs(flags, args); err != nil {
+		return config, err
+	}
+
+	config.ConfigFile = configFile
+	config.IgnoredVariables = ignoredVariables
+	return config, config.Validate()
+}
+
+// parseArgs leaves reporting errors to the caller, only the usage requested
+// with -h is printed
+func parseArgs(flags *flag.FlagSet, args []string) error {
+	flags.SetOutput(io.Discard)
+	err := flags.Parse(args)
+
+	if errors.Is(err, flag.ErrHelp) {
+		flags.SetOutput(os.Stderr)
+		flags.Usage()
+	}
+
+	return err
+}
+
+// applyConfigFile sets options from a yaml (or json) file. Nested keys are
+// joined with dashes, so `cache: {size: 100}` sets `cache-size`, and lists are
+// joined with commas.
+func applyConfigFile(flags *flag.FlagSet, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	document := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	options := map[string]string{}
+	flattenOptions("", document, options)
+
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if name == "config" || flags.Lookup(name) == nil {
+			return fmt.Errorf("%s: unknown option %q", path, name)
+		}
+
+		if err := flags.Set(name, options[name]); err != nil {
+			return fmt.Errorf("%s: invalid value %q for %s: %w", path, options[name], name, err)
+		}
+	}
+
+	return nil
+}
+
+func flattenOptions(prefix string, values map[string]interface{}, options map[string]string) {
+	for key, value := range values {
+		name := prefix + key
+
+		switch value := value.(type) {
+		case map[string]interface{}:
+			flattenOptions(name+"-", value, options)
+		case []interface{}:
+			items := make([]string, len(value))
+			for i, item := range value {
+				items[i] = fmt.Sprint(item)
+			}
+			options[name] = strings.Join(items, ",")
+		case nil:
+			options[name] = ""
+		default:
+			options[name] = fmt.Sprint(value)
+		}
+	}
+}
+
+// applyEnvironment sets options from SITELOOK_* variables named after the
+// flags e.g. SITELOOK_CACHE_SIZE sets `cache-size`. Variables not matching any
+// option are returned instead of failing, since the prefix isn't only used by
+// sitelook.
+func applyEnvironment(flags *flag.FlagSet, environment []string) (ignored []string, err error) {
+	sort.Strings(environment)
+	ignored = []string{}
+
+	for _, variable := range environment {
+		key, value, _ := strings.Cut(variable, "=")
+		if !strings.HasPrefix(key, envPrefix) || key == configFileEnv {
+			continue
+		}
+
+		name := strings.ReplaceAll(strings.ToLower(strings.TrimPrefix(key, envPrefix)), "_", "-")
+		if flags.Lookup(name) == nil {
+			ignored = append(ignored, key)
+			continue
+		}
+
+		if err := flags.Set(name, value); err != nil {
+			return nil, fmt.Errorf("invalid value %q for %s: %w", value, key, err)
+		}
+	}
+
+	return ignored, nil
+}
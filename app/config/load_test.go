@@ -0,0 +1,236 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func loadDefaults(t *testing.T) Config {
+	t.Helper()
+
+	config, err := Load("sitelook", []string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return config
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		env    map[string]string
+		args   []string
+		change func(config *Config)
+	}{
+		{
+			name:   "defaults",
+			change: func(config *Config) {},
+		},
+		{
+			name: "flags",
+			args: []string{"-listen", ":3000", "-cache-size", "10", "-log-queries"},
+			change: func(config *Config) {
+				config.Listen = ":3000"
+				config.CacheSize = 10
+				config.LogQueries = true
+			},
+		},
+		{
+			name: "nested keys of the file",
+			file: "listen: \":3000\"\ncache:\n  size: 100\n  ttl: 1m\ndisk-cache:\n  dir: \"\"\n",
+			change: func(config *Config) {
+				config.Listen = ":3000"
+				config.CacheSize = 100
+				config.CacheTtl = time.Minute
+			},
+		},
+		{
+			name: "lists of the file",
+			file: "metasearch-backends: [google, bing]\nfallback-backends:\n  - duckduckgo\n",
+			change: func(config *Config) {
+				config.MetasearchBackends = []string{"google", "bing"}
+				config.FallbackBackends = []string{"duckduckgo"}
+			},
+		},
+		{
+			name: "json file",
+			file: `{"cache": {"size": 100}, "log-queries": true}`,
+			change: func(config *Config) {
+				config.CacheSize = 100
+				config.LogQueries = true
+			},
+		},
+		{
+			name: "environment overrides the file",
+			file: "cache:\n  size: 100\n  ttl: 1m\n",
+			env:  map[string]string{"SITELOOK_CACHE_SIZE": "200", "SITELOOK_METASEARCH_BACKENDS": "bing, google"},
+			change: func(config *Config) {
+				config.CacheSize = 200
+				config.CacheTtl = time.Minute
+				config.MetasearchBackends = []string{"bing", "google"}
+			},
+		},
+		{
+			name: "flags override the environment",
+			file: "cache:\n  size: 100\n",
+			env:  map[string]string{"SITELOOK_CACHE_SIZE": "200"},
+			args: []string{"-cache-size", "300"},
+			change: func(config *Config) {
+				config.CacheSize = 300
+			},
+		},
+		{
+			name: "unknown environment variables are ignored",
+			env:  map[string]string{"SITELOOK_SERVICE_HOST": "10.0.0.1", "SITELOOK_PORT": "tcp://10.0.0.1:80"},
+			change: func(config *Config) {
+				config.IgnoredVariables = []string{"SITELOOK_PORT", "SITELOOK_SERVICE_HOST"}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			want := loadDefaults(t)
+
+			args := test.args
+			if len(test.file) > 0 {
+				path := filepath.Join(t.TempDir(), "sitelook.yaml")
+				if err := os.WriteFile(path, []byte(test.file), 0644); err != nil {
+					t.Fatal(err)
+				}
+
+				args = append([]string{"-config", path}, args...)
+				want.ConfigFile = path
+			}
+
+			for key, value := range test.env {
+				t.Setenv(key, value)
+			}
+
+			test.change(&want)
+
+			got, err := Load("sitelook", args)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v\nwant %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFileFromEnvironment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sitelook.yaml")
+	if err := os.WriteFile(path, []byte("cache-size: 100\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("SITELOOK_CONFIG", path)
+
+	config, err := Load("sitelook", []string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.ConfigFile != path || config.CacheSize != 100 {
+		t.Errorf("got config file %q and cache size %d, want %q and 100", config.ConfigFile, config.CacheSize, path)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		env     map[string]string
+		args    []string
+		wantErr []string
+	}{
+		{
+			name:    "unknown flag",
+			args:    []string{"-cache-sise", "10"},
+			wantErr: []string{"cache-sise"},
+		},
+		{
+			name:    "unknown option in the file",
+			file:    "cache:\n  sise: 10\n",
+			wantErr: []string{`unknown option "cache-sise"`},
+		},
+		{
+			name:    "config file in the file",
+			file:    "config: other.yaml\n",
+			wantErr: []string{`unknown option "config"`},
+		},
+		{
+			name:    "invalid value in the file",
+			file:    "cache-size: many\n",
+			wantErr: []string{`invalid value "many" for cache-size`},
+		},
+		{
+			name:    "invalid file",
+			file:    "cache: [\n",
+			wantErr: []string{"sitelook.yaml"},
+		},
+		{
+			name:    "invalid environment variable",
+			env:     map[string]string{"SITELOOK_CACHE_TTL": "forever"},
+			wantErr: []string{`invalid value "forever" for SITELOOK_CACHE_TTL`},
+		},
+		{
+			name:    "every invalid option is reported",
+			args:    []string{"-listen", "nowhere", "-breaker-threshold", "0", "-cache-size", "-1"},
+			wantErr: []string{"listen", "breaker-threshold", "cache-size"},
+		},
+		{
+			name:    "invalid proxy",
+			args:    []string{"-proxy", "ftp://proxy.example:21"},
+			wantErr: []string{"proxy"},
+		},
+		{
+			name:    "isolation without a socks5 proxy",
+			args:    []string{"-proxy-isolation"},
+			wantErr: []string{"proxy-isolation"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args := test.args
+			if len(test.file) > 0 {
+				path := filepath.Join(t.TempDir(), "sitelook.yaml")
+				if err := os.WriteFile(path, []byte(test.file), 0644); err != nil {
+					t.Fatal(err)
+				}
+				args = append([]string{"-config", path}, args...)
+			}
+
+			for key, value := range test.env {
+				t.Setenv(key, value)
+			}
+
+			_, err := Load("sitelook", args)
+			if err == nil {
+				t.Fatal("got no error")
+			}
+
+			for _, want := range test.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q doesn't mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadExampleFile(t *testing.T) {
+	if _, err := Load("sitelook", []string{"-config", filepath.Join("..", "..", "sitelook.example.yaml")}); err != nil {
+		t.Errorf("example file doesn't load: %s", err)
+	}
+}
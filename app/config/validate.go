@@ -0,0 +1,172 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Languages are language codes with an optional region e.g. `en` or `pt-BR`
+var languagePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z]{2})?$`)
+
+var (
+	webSchemes   = []string{"http", "https"}
+	proxySchemes = []string{"http", "https", "socks5", "socks5h"}
+)
+
+// Validate checks the options which can be checked without starting the
+// server and reports all invalid ones at once
+func (c Config) Validate() error {
+	errs := []error{}
+
+	check := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	check(validateAddress("listen", c.Listen, true))
+	check(validateAddress("admin-addr", c.AdminAddr, false))
+	check(validateUrl("base-url", c.BaseUrl, webSchemes, false))
+	check(validateUrl("google-url", c.GoogleUrl, webSchemes, true))
+	check(validateUrl("searxng-url", c.SearxngUrl, webSchemes, false))
+	for _, proxy := range c.Proxies {
+		check(validateUrl("proxy", proxy, proxySchemes, true))
+	}
+
+	check(validatePositiveDuration("read-header-timeout", c.ReadHeaderTimeout))
+	check(validatePositiveDuration("read-timeout", c.ReadTimeout))
+	check(validatePositiveDuration("write-timeout", c.WriteTimeout))
+	check(validatePositiveDuration("idle-timeout", c.IdleTimeout))
+	check(validatePositiveDuration("shutdown-timeout", c.ShutdownTimeout))
+	check(validatePositiveDuration("metasearch-timeout", c.MetasearchTimeout))
+	check(validatePositiveDuration("breaker-cooldown", c.BreakerCooldown))
+	check(validatePositiveDuration("upstream-timeout", c.UpstreamTimeout))
+	check(validatePositiveDuration("cache-ttl", c.CacheTtl))
+	check(validatePositiveDuration("disk-cache-ttl", c.DiskCacheTtl))
+	check(validatePositiveDuration("fingerprint-session", c.FingerprintSession))
+
+	if c.ShutdownDelay < 0 {
+		errs = append(errs, errors.New("shutdown-delay must not be negative"))
+	}
+
+	// responses would be cut off while waiting for the backend
+	if c.WriteTimeout <= c.UpstreamTimeout {
+		errs = append(errs, fmt.Errorf("write-timeout (%s) must be longer than upstream-timeout (%s)", c.WriteTimeout, c.UpstreamTimeout))
+	}
+
+	if c.BreakerThreshold <= 0 {
+		errs = append(errs, errors.New("breaker-threshold must be positive"))
+	}
+
+	if c.CacheSize < 0 {
+		errs = append(errs, errors.New("cache-size must not be negative"))
+	}
+
+	if c.MaxResponseSize <= 0 {
+		errs = append(errs, errors.New("max-response-size must be positive"))
+	}
+
+	if c.DiskCacheSize <= 0 {
+		errs = append(errs, errors.New("disk-cache-size must be positive"))
+	}
+
+	if c.BreakageThreshold <= 0 || c.BreakageThreshold > 1 {
+		errs = append(errs, errors.New("breakage-threshold must be between 0 and 1"))
+	}
+
+	if c.BreakageWindow <= 0 {
+		errs = append(errs, errors.New("breakage-window must be positive"))
+	}
+
+	if len(c.RecordDir) > 0 && len(c.ReplayDir) > 0 {
+		errs = append(errs, errors.New("record and replay can't be enabled at the same time"))
+	}
+
+	if c.ProxyRotation != "round-robin" && c.ProxyRotation != "health" {
+		errs = append(errs, fmt.Errorf("proxy-rotation must be either round-robin or health, got %q", c.ProxyRotation))
+	}
+
+	if c.ProxyIsolation && !slices.ContainsFunc(c.Proxies, isSocksProxy) {
+		errs = append(errs, errors.New("proxy-isolation requires a socks5 proxy"))
+	}
+
+	if c.FingerprintRotation != "request" && c.FingerprintRotation != "session" {
+		errs = append(errs, fmt.Errorf("fingerprint-rotation must be either request or session, got %q", c.FingerprintRotation))
+	}
+
+	if len(c.UserAgent) == 0 {
+		errs = append(errs, errors.New("user-agent must not be empty"))
+	}
+
+	if len(c.Language) > 0 && !languagePattern.MatchString(c.Language) {
+		errs = append(errs, fmt.Errorf("language %q isn't a language code like `en` or `pt-BR`", c.Language))
+	}
+
+	if c.LogLevel != "debug" && c.LogLevel != "info" && c.LogLevel != "warn" && c.LogLevel != "error" {
+		errs = append(errs, fmt.Errorf("log-level must be one of debug, info, warn and error, got %q", c.LogLevel))
+	}
+
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		errs = append(errs, fmt.Errorf("log-format must be either text or json, got %q", c.LogFormat))
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateAddress(name string, address string, required bool) error {
+	if len(address) == 0 {
+		if required {
+			return fmt.Errorf("%s must not be empty", name)
+		}
+		return nil
+	}
+
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%s %q isn't a host:port address: %w", name, address, err)
+	}
+
+	if portNumber, err := strconv.Atoi(port); err != nil || portNumber < 0 || portNumber > 65535 {
+		return fmt.Errorf("%s %q has an invalid port", name, address)
+	}
+
+	return nil
+}
+
+func validateUrl(name string, value string, schemes []string, required bool) error {
+	if len(value) == 0 {
+		if required {
+			return fmt.Errorf("%s must not be empty", name)
+		}
+		return nil
+	}
+
+	parsedUrl, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	if !slices.Contains(schemes, parsedUrl.Scheme) || len(parsedUrl.Host) == 0 {
+		return fmt.Errorf("%s %q must be an absolute url with one of the schemes %v", name, value, schemes)
+	}
+
+	return nil
+}
+
+func validatePositiveDuration(name string, duration time.Duration) error {
+	if duration <= 0 {
+		return fmt.Errorf("%s must be positive", name)
+	}
+	return nil
+}
+
+func isSocksProxy(proxy string) bool {
+	return strings.HasPrefix(proxy, "socks5://") || strings.HasPrefix(proxy, "socks5h://")
+}
@@ -0,0 +1,123 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"sitelook/app/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Cancelled requests are given a moment to respond with an error page before
+// their connections are closed
+const cancelGracePeriod = time.Second
+
+// ready is reported at /readyz, it's cleared once the server starts shutting
+// down so load balancers stop sending requests to it
+var ready atomic.Bool
+
+// listen and notifySignals are replaced in tests
+var listen = net.Listen
+var notifySignals = signal.Notify
+
+func readyRoute(c *gin.Context) {
+	if !ready.Load() {
+		c.String(http.StatusServiceUnavailable, "shutting down")
+		return
+	}
+	c.String(http.StatusOK, "ok")
+}
+
+func newHttpServer(address string, handler http.Handler, config config.Config, baseContext context.Context) *http.Server {
+	return &http.Server{
+		Addr:              address,
+		Handler:           handler,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		ReadTimeout:       config.ReadTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
+		BaseContext: func(net.Listener) context.Context {
+			return baseContext
+		},
+	}
+}
+
+// serve runs the servers until SIGINT or SIGTERM. Active requests are given
+// the shutdown timeout to finish, after that cancelRequests cancels their
+// upstream requests and the remaining connections are closed.
+func serve(servers []*http.Server, config config.Config, cancelRequests context.CancelFunc) {
+	// the addresses are bound and the signals handled before /readyz reports
+	// the server ready, a taken address fails the start
+	listeners := make([]net.Listener, len(servers))
+	for i, server := range servers {
+		listener, err := listen("tcp", server.Addr)
+		if err != nil {
+			fatal(err)
+		}
+		listeners[i] = listener
+	}
+
+	signals := make(chan os.Signal, 1)
+	notifySignals(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	ready.Store(true)
+
+	serverErrors := make(chan error, len(servers))
+
+	for i, server := range servers {
+		go func(server *http.Server, listener net.Listener) {
+			slog.Info("listening", "address", listener.Addr().String())
+			if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+				serverErrors <- err
+			}
+		}(server, listeners[i])
+	}
+
+	select {
+	case err := <-serverErrors:
+		fatal(err)
+	case received := <-signals:
+		slog.Info("shutting down", "signal", received.String(), "timeout", config.ShutdownTimeout)
+	}
+
+	ready.Store(false)
+	signal.Stop(signals)
+	time.Sleep(config.ShutdownDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, server := range servers {
+		wg.Add(1)
+		go func(server *http.Server) {
+			defer wg.Done()
+
+			if err := server.Shutdown(ctx); err != nil {
+				slog.Warn("requests didn't finish in time, cancelling them", "address", server.Addr, "error", err)
+				cancelRequests()
+
+				graceCtx, cancelGrace := context.WithTimeout(context.Background(), cancelGracePeriod)
+				defer cancelGrace()
+
+				if err := server.Shutdown(graceCtx); err != nil {
+					server.Close()
+				}
+			}
+		}(server)
+	}
+	wg.Wait()
+
+	cancelRequests()
+	slog.Info("server stopped")
+}
@@ -0,0 +1,116 @@
+package app
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"sitelook/app/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// startServer runs serve on a free port and returns the bound address, the
+// channel the signals are delivered to and a channel closed once serve
+// returned
+func startServer(t *testing.T, config config.Config) (address string, signals chan<- os.Signal, stopped <-chan struct{}) {
+	t.Helper()
+
+	previousListen, previousNotify := listen, notifySignals
+	t.Cleanup(func() { listen, notifySignals = previousListen, previousNotify })
+
+	addresses := make(chan string, 1)
+	listen = func(network string, address string) (net.Listener, error) {
+		if ready.Load() {
+			t.Error("ready before the address was bound")
+		}
+
+		listener, err := net.Listen(network, address)
+		if err == nil {
+			addresses <- listener.Addr().String()
+		}
+		return listener, err
+	}
+
+	signalChannels := make(chan chan<- os.Signal, 1)
+	notifySignals = func(c chan<- os.Signal, sig ...os.Signal) {
+		signalChannels <- c
+	}
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/readyz", readyRoute)
+
+	ctx, cancelRequests := context.WithCancel(context.Background())
+	server := newHttpServer("127.0.0.1:0", engine, config, ctx)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		serve([]*http.Server{server}, config, cancelRequests)
+	}()
+
+	return <-addresses, <-signalChannels, done
+}
+
+func getReady(address string) (int, error) {
+	client := &http.Client{Timeout: time.Second}
+
+	res, err := client.Get("http://" + address + "/readyz")
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	io.Copy(io.Discard, res.Body)
+	return res.StatusCode, nil
+}
+
+func TestReadyz(t *testing.T) {
+	ready.Store(false)
+	t.Cleanup(func() { ready.Store(false) })
+
+	address, signals, stopped := startServer(t, config.Config{
+		ShutdownTimeout: time.Second,
+		ShutdownDelay:   time.Second,
+	})
+
+	if status, err := getReady(address); err != nil || status != http.StatusOK {
+		t.Fatalf("got status %d, error %v, want the bound server to be ready", status, err)
+	}
+
+	shutdownAt := time.Now()
+	signals <- syscall.SIGTERM
+
+	// /readyz fails while requests are still accepted during the shutdown delay
+	status := http.StatusOK
+	for status == http.StatusOK && time.Since(shutdownAt) < 500*time.Millisecond {
+		var err error
+		if status, err = getReady(address); err != nil {
+			t.Fatalf("request during the shutdown delay failed: %s", err)
+		}
+	}
+
+	if status != http.StatusServiceUnavailable {
+		t.Errorf("got status %d, want %d once the shutdown began", status, http.StatusServiceUnavailable)
+	}
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server didn't stop")
+	}
+
+	if time.Since(shutdownAt) < time.Second {
+		t.Errorf("server stopped before the shutdown delay passed")
+	}
+
+	if _, err := getReady(address); err == nil {
+		t.Errorf("server accepts requests after it stopped")
+	}
+}
@@ -0,0 +1,292 @@
+package imgproxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"sitelook/app/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+const RoutePath = "/imgproxy"
+
+// Thumbnails are small, anything bigger is most likely not a thumbnail
+const maxImageSize = 5 << 20
+
+// Thumbnails are redirected to cdns at most once or twice
+const maxRedirects = 3
+
+var errForbiddenAddress = errors.New("image url points to a private address")
+
+var enabled = true
+var signingKey = generateKey()
+
+// Images are requested from urls in result pages, which must not reach
+// the instance's own network
+var dialer = net.Dialer{
+	Timeout: 5 * time.Second,
+	Control: refusePrivateAddress,
+}
+
+var client = http.Client{
+	Transport:     newTransport(),
+	CheckRedirect: checkRedirect,
+	Timeout:       10 * time.Second,
+}
+
+var userAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36"
+
+// A random key is used until one is configured. Urls signed with it don't
+// survive restarts, which is fine for result pages.
+func generateKey() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func SetEnabled(isEnabled bool) {
+	enabled = isEnabled
+}
+
+func SetKey(key []byte) {
+	signingKey = key
+}
+
+func SetUserAgent(agent string) {
+	userAgent = agent
+}
+
+// SetProxyTransport sends image requests through a transport connecting to
+// outbound proxies, nil restores direct requests
+func SetProxyTransport(transport http.RoundTripper) {
+	if transport == nil {
+		client.Transport = newTransport()
+		return
+	}
+
+	client.Transport = &checkedTransport{transport: transport}
+}
+
+// lookupIPAddr resolves the hosts of images requested through proxies
+var lookupIPAddr = net.DefaultResolver.LookupIPAddr
+
+// checkedTransport refuses hosts resolving to private addresses before the
+// request is handed to a proxy. Proxies resolve hosts themselves and are
+// often on the instance's network, where the dialer's check doesn't apply.
+type checkedTransport struct {
+	transport http.RoundTripper
+}
+
+func (t *checkedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	addresses, err := lookupIPAddr(req.Context(), req.URL.Hostname())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, address := range addresses {
+		if isPrivateIp(address.IP) {
+			return nil, errForbiddenAddress
+		}
+	}
+
+	return t.transport.RoundTrip(req)
+}
+
+// newTransport refuses private addresses when it connects to image servers
+func newTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+func refusePrivateAddress(network string, address string, conn syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); ip == nil || isPrivateIp(ip) {
+		return errForbiddenAddress
+	}
+
+	return nil
+}
+
+func isPrivateIp(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified()
+}
+
+// checkImageUrl refuses urls which aren't http(s) or name a private host
+func checkImageUrl(imageUrl *url.URL) error {
+	if imageUrl.Scheme != "http" && imageUrl.Scheme != "https" {
+		return fmt.Errorf("unsupported image url scheme %q", imageUrl.Scheme)
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(imageUrl.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errForbiddenAddress
+	}
+
+	if ip := net.ParseIP(host); ip != nil && isPrivateIp(ip) {
+		return errForbiddenAddress
+	}
+
+	return nil
+}
+
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return errors.New("image request was redirected too many times")
+	}
+
+	return checkImageUrl(req.URL)
+}
+
+func sign(imageUrl string) string {
+	mac := hmac.New(sha256.New, signingKey)
+	mac.Write([]byte(imageUrl))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func verify(imageUrl string, signature string) bool {
+	expected, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, signingKey)
+	mac.Write([]byte(imageUrl))
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// ProxyUrl returns a signed url of the image proxy serving the image. Inline
+// `data:` images are returned as they are.
+func ProxyUrl(imageUrl string) string {
+	if !enabled || len(imageUrl) == 0 || strings.HasPrefix(imageUrl, "data:image/") {
+		return imageUrl
+	}
+
+	query := url.Values{}
+	query.Set("url", imageUrl)
+	query.Set("sig", sign(imageUrl))
+
+	return RoutePath + "?" + query.Encode()
+}
+
+// Svg images are not proxied since they can contain scripts which would run
+// on the instance's origin
+func isAllowedContentType(contentType string) bool {
+	return strings.HasPrefix(contentType, "image/") && !strings.HasPrefix(contentType, "image/svg")
+}
+
+func fetchImage(ctx context.Context, imageUrl string) (*http.Response, error) {
+	parsedUrl, err := url.Parse(imageUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkImageUrl(parsedUrl); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", imageUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header = http.Header{
+		"Accept":     {"image/avif,image/webp,image/apng,image/*;q=0.8"},
+		"User-Agent": {userAgent},
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("image request failed with status %d", res.StatusCode)
+	}
+
+	if !isAllowedContentType(res.Header.Get("Content-Type")) {
+		res.Body.Close()
+		return nil, fmt.Errorf("unsupported image content type %q", res.Header.Get("Content-Type"))
+	}
+
+	if res.ContentLength > maxImageSize {
+		res.Body.Close()
+		return nil, errors.New("image is too big")
+	}
+
+	// images without a content length are read before they are served, so
+	// the ones over the size limit are refused instead of being cut off
+	if res.ContentLength < 0 {
+		body, err := io.ReadAll(io.LimitReader(res.Body, maxImageSize+1))
+		res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(body) > maxImageSize {
+			return nil, errors.New("image is too big")
+		}
+
+		res.Body = io.NopCloser(bytes.NewReader(body))
+		res.ContentLength = int64(len(body))
+	}
+
+	return res, nil
+}
+
+func ProxyRoute(c *gin.Context) {
+	imageUrl := c.Query("url")
+
+	if !verify(imageUrl, c.Query("sig")) {
+		c.String(http.StatusForbidden, "invalid signature")
+		return
+	}
+
+	res, err := fetchImage(c.Request.Context(), imageUrl)
+	if err != nil {
+		slog.Warn("image proxy failed", "error", err)
+		c.Status(http.StatusBadGateway)
+		return
+	}
+
+	defer res.Body.Close()
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", res.Header.Get("Content-Type"))
+	header.Set("Cache-Control", "private, max-age=86400")
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Content-Security-Policy", "default-src 'none'")
+	header.Set("Content-Length", strconv.FormatInt(res.ContentLength, 10))
+
+	c.Status(http.StatusOK)
+
+	written, err := io.Copy(c.Writer, res.Body)
+	metrics.AddImageProxyBytes(written)
+	if err != nil {
+		slog.Warn("image proxy failed", "error", err)
+	}
+}
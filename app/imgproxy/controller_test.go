@@ -0,0 +1,314 @@
+package imgproxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+var png = []byte("\x89PNG\r\n\x1a\n")
+
+// serveImages answers image requests to any host with the handler. Image
+// servers in tests listen on loopback addresses, so the transport connects
+// to the handler without the private address check of the dialer.
+func serveImages(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	previousTransport := client.Transport
+	client.Transport = &http.Transport{
+		DialContext: func(ctx context.Context, network string, address string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+		},
+	}
+	t.Cleanup(func() { client.Transport = previousTransport })
+}
+
+// requestImage requests the image through the proxy route with the query
+func requestImage(t *testing.T, query url.Values) *httptest.ResponseRecorder {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET(RoutePath, ProxyRoute)
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, RoutePath+"?"+query.Encode(), nil))
+	return recorder
+}
+
+func signedQuery(imageUrl string) url.Values {
+	return url.Values{"url": {imageUrl}, "sig": {sign(imageUrl)}}
+}
+
+func TestProxyUrl(t *testing.T) {
+	imageUrl := "https://images.example/thumbnail.png?size=small"
+
+	proxyUrl, err := url.Parse(ProxyUrl(imageUrl))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if proxyUrl.Path != RoutePath || proxyUrl.Query().Get("url") != imageUrl || !verify(imageUrl, proxyUrl.Query().Get("sig")) {
+		t.Errorf("got %s, want a signed url of the image proxy", proxyUrl)
+	}
+
+	if inline := "data:image/png;base64,iVBORw0KGgo="; ProxyUrl(inline) != inline {
+		t.Errorf("inline image was proxied")
+	}
+}
+
+func TestProxyRouteSignature(t *testing.T) {
+	serveImages(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(png)
+	})
+
+	imageUrl := "http://images.example/thumbnail.png"
+
+	tests := []struct {
+		name       string
+		query      url.Values
+		wantStatus int
+	}{
+		{name: "valid signature", query: signedQuery(imageUrl), wantStatus: http.StatusOK},
+		{name: "no signature", query: url.Values{"url": {imageUrl}}, wantStatus: http.StatusForbidden},
+		{name: "signature of another url", query: url.Values{"url": {imageUrl + "?other"}, "sig": {sign(imageUrl)}}, wantStatus: http.StatusForbidden},
+		{name: "tampered signature", query: url.Values{"url": {imageUrl}, "sig": {strings.ToUpper(sign(imageUrl))}}, wantStatus: http.StatusForbidden},
+		{name: "invalid signature", query: url.Values{"url": {imageUrl}, "sig": {"not base64!"}}, wantStatus: http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := requestImage(t, test.query)
+
+			if recorder.Code != test.wantStatus {
+				t.Fatalf("got status %d, want %d", recorder.Code, test.wantStatus)
+			}
+
+			if test.wantStatus == http.StatusOK && !bytes.Equal(recorder.Body.Bytes(), png) {
+				t.Errorf("got body %q, want the image", recorder.Body)
+			}
+		})
+	}
+}
+
+func TestProxyRouteRefusesPrivateAddresses(t *testing.T) {
+	var requests atomic.Int32
+	serveImages(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.Redirect(w, r, r.URL.Query().Get("to"), http.StatusFound)
+	})
+
+	tests := []struct {
+		name     string
+		imageUrl string
+	}{
+		{name: "loopback", imageUrl: "http://127.0.0.1/thumbnail.png"},
+		{name: "private", imageUrl: "http://10.0.0.1/thumbnail.png"},
+		{name: "link-local", imageUrl: "http://169.254.169.254/latest/meta-data/"},
+		{name: "ipv6 loopback", imageUrl: "http://[::1]/thumbnail.png"},
+		{name: "localhost", imageUrl: "http://localhost./thumbnail.png"},
+		{name: "unsupported scheme", imageUrl: "file:///etc/passwd"},
+		{name: "redirect to loopback", imageUrl: "http://images.example/redirect?to=http://127.0.0.1/thumbnail.png"},
+		{name: "redirect to localhost", imageUrl: "http://images.example/redirect?to=http://localhost/thumbnail.png"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if recorder := requestImage(t, signedQuery(test.imageUrl)); recorder.Code != http.StatusBadGateway {
+				t.Errorf("got status %d, want %d", recorder.Code, http.StatusBadGateway)
+			}
+		})
+	}
+
+	// only the redirecting requests reached the image server
+	if got := requests.Load(); got != 2 {
+		t.Errorf("image server got %d requests, want 2", got)
+	}
+}
+
+func TestDialerRefusesPrivateAddresses(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	// localhost passes as a name and is refused once it is resolved
+	for _, address := range []string{net.JoinHostPort("localhost", port), listener.Addr().String()} {
+		conn, err := dialer.DialContext(context.Background(), "tcp4", address)
+		if err == nil {
+			conn.Close()
+		}
+
+		if !errors.Is(err, errForbiddenAddress) {
+			t.Errorf("dialing %s got error %v, want %v", address, err, errForbiddenAddress)
+		}
+	}
+
+	if err := refusePrivateAddress("tcp4", "93.184.215.14:443", nil); err != nil {
+		t.Errorf("public address was refused: %s", err)
+	}
+}
+
+func TestProxyRouteContentTypes(t *testing.T) {
+	tests := []struct {
+		contentType string
+		wantStatus  int
+	}{
+		{contentType: "image/png", wantStatus: http.StatusOK},
+		{contentType: "image/webp", wantStatus: http.StatusOK},
+		{contentType: "image/svg+xml", wantStatus: http.StatusBadGateway},
+		{contentType: "text/html", wantStatus: http.StatusBadGateway},
+		{contentType: "", wantStatus: http.StatusBadGateway},
+	}
+
+	for _, test := range tests {
+		t.Run(test.contentType, func(t *testing.T) {
+			serveImages(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", test.contentType)
+				w.Write(png)
+			})
+
+			recorder := requestImage(t, signedQuery("http://images.example/thumbnail"))
+			if recorder.Code != test.wantStatus {
+				t.Fatalf("got status %d, want %d", recorder.Code, test.wantStatus)
+			}
+
+			if test.wantStatus == http.StatusOK {
+				header := recorder.Header()
+				if header.Get("Content-Type") != test.contentType || header.Get("X-Content-Type-Options") != "nosniff" || header.Get("Content-Security-Policy") != "default-src 'none'" {
+					t.Errorf("got headers %v", header)
+				}
+			}
+		})
+	}
+}
+
+func TestProxyRouteSizeLimit(t *testing.T) {
+	tests := []struct {
+		name          string
+		size          int
+		contentLength bool
+		wantStatus    int
+	}{
+		{name: "with content length", size: 1024, contentLength: true, wantStatus: http.StatusOK},
+		{name: "too big with content length", size: maxImageSize + 1, contentLength: true, wantStatus: http.StatusBadGateway},
+		{name: "without content length", size: 1024, wantStatus: http.StatusOK},
+		{name: "maximum size without content length", size: maxImageSize, wantStatus: http.StatusOK},
+		{name: "too big without content length", size: maxImageSize + 1, wantStatus: http.StatusBadGateway},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			serveImages(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "image/png")
+				if test.contentLength {
+					w.Header().Set("Content-Length", strconv.Itoa(test.size))
+				} else {
+					// flushing before the body is written sends it chunked
+					w.WriteHeader(http.StatusOK)
+					w.(http.Flusher).Flush()
+				}
+				w.Write(bytes.Repeat([]byte{0}, test.size))
+			})
+
+			recorder := requestImage(t, signedQuery("http://images.example/thumbnail.png"))
+			if recorder.Code != test.wantStatus {
+				t.Fatalf("got status %d, want %d", recorder.Code, test.wantStatus)
+			}
+
+			if test.wantStatus == http.StatusOK {
+				if got := recorder.Header().Get("Content-Length"); got != strconv.Itoa(test.size) || recorder.Body.Len() != test.size {
+					t.Errorf("got content length %s and %d bytes, want %d", got, recorder.Body.Len(), test.size)
+				}
+			}
+		})
+	}
+}
+
+// roundTripperFunc answers requests without connecting anywhere
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestProxyTransportRefusesPrivateNames(t *testing.T) {
+	previousLookup, previousTransport := lookupIPAddr, client.Transport
+	t.Cleanup(func() { lookupIPAddr, client.Transport = previousLookup, previousTransport })
+
+	addresses := map[string]string{
+		"images.example":           "93.184.215.14",
+		"metadata.google.internal": "169.254.169.254",
+		"intranet.corp.example":    "10.1.2.3",
+	}
+	lookupIPAddr = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		address, ok := addresses[host]
+		if !ok {
+			return nil, errors.New("no such host")
+		}
+		return []net.IPAddr{{IP: net.ParseIP(address)}}, nil
+	}
+
+	var proxied []string
+	SetProxyTransport(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		proxied = append(proxied, req.URL.Host)
+
+		if to := req.URL.Query().Get("to"); len(to) > 0 {
+			return &http.Response{StatusCode: http.StatusFound, Header: http.Header{"Location": {to}}, Body: http.NoBody, Request: req}, nil
+		}
+
+		return &http.Response{
+			StatusCode:    http.StatusOK,
+			Header:        http.Header{"Content-Type": {"image/png"}},
+			Body:          io.NopCloser(bytes.NewReader(png)),
+			ContentLength: int64(len(png)),
+			Request:       req,
+		}, nil
+	}))
+
+	tests := []struct {
+		name        string
+		imageUrl    string
+		wantStatus  int
+		wantProxied []string
+	}{
+		{name: "public host", imageUrl: "http://images.example/thumbnail.png", wantStatus: http.StatusOK, wantProxied: []string{"images.example"}},
+		{name: "metadata server", imageUrl: "http://metadata.google.internal/computeMetadata/v1/", wantStatus: http.StatusBadGateway},
+		{name: "private host", imageUrl: "http://intranet.corp.example/thumbnail.png", wantStatus: http.StatusBadGateway},
+		{name: "unknown host", imageUrl: "http://unknown.example/thumbnail.png", wantStatus: http.StatusBadGateway},
+		{name: "redirect to a private host", imageUrl: "http://images.example/redirect?to=http://intranet.corp.example/thumbnail.png", wantStatus: http.StatusBadGateway, wantProxied: []string{"images.example"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			proxied = nil
+
+			if recorder := requestImage(t, signedQuery(test.imageUrl)); recorder.Code != test.wantStatus {
+				t.Errorf("got status %d, want %d", recorder.Code, test.wantStatus)
+			}
+
+			if !reflect.DeepEqual(proxied, test.wantProxied) {
+				t.Errorf("got proxied hosts %v, want %v", proxied, test.wantProxied)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Search terms are left out of the logs unless query logging is enabled
+var queryLogging = false
+
+// Parsed pages are logged at the debug level in the debug mode
+var pageDumps = false
+
+// Setup replaces the default logger, the level is one of `debug`, `info`,
+// `warn` and `error` and the format is either `text` or `json`. Messages of
+// the standard logger go through it as well.
+func Setup(level string, format string) error {
+	var logLevel slog.Level
+	if err := logLevel.UnmarshalText([]byte(level)); err != nil {
+		return errors.New("log level must be one of debug, info, warn and error")
+	}
+
+	options := &slog.HandlerOptions{Level: logLevel}
+
+	var handler slog.Handler
+	switch format {
+	case "text":
+		handler = slog.NewTextHandler(os.Stderr, options)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, options)
+	default:
+		return errors.New("log format must be either text or json")
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+func SetQueryLogging(enabled bool) {
+	queryLogging = enabled
+}
+
+func SetPageDumps(enabled bool) {
+	pageDumps = enabled
+}
+
+// DumpPage logs the whole parsed page including the search term, it's only
+// done in the debug mode
+func DumpPage(c *gin.Context, page interface{}) {
+	if !pageDumps {
+		return
+	}
+
+	data, err := json.Marshal(page)
+	if err != nil {
+		Logger(c).Warn("page dump failed", "error", err)
+		return
+	}
+
+	Logger(c).Debug("parsed page", "page", json.RawMessage(data))
+}
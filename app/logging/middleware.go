@@ -0,0 +1,106 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const RequestIdHeader = "X-Request-Id"
+
+const (
+	requestIdKey = "logging.requestId"
+	attrsKey     = "logging.attrs"
+)
+
+// Request ids from a reverse proxy are reused when they look sane
+const maxRequestIdLength = 64
+
+func generateRequestId() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(id)
+}
+
+func isValidRequestId(id string) bool {
+	if len(id) == 0 || len(id) > maxRequestIdLength {
+		return false
+	}
+
+	for _, char := range id {
+		isAlphanumeric := (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9')
+		if !isAlphanumeric && char != '-' && char != '_' && char != '.' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Middleware assigns every request an id, returned in the X-Request-Id header,
+// and logs the request once it's handled. Handlers add attributes like the
+// backend or the result count with AddAttrs.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
+
+		requestId := c.GetHeader(RequestIdHeader)
+		if !isValidRequestId(requestId) {
+			requestId = generateRequestId()
+		}
+
+		c.Set(requestIdKey, requestId)
+		c.Header(RequestIdHeader, requestId)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []slog.Attr{
+			slog.String("requestId", requestId),
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(started)),
+		}
+
+		if queryLogging && len(c.Request.URL.RawQuery) > 0 {
+			attrs = append(attrs, slog.String("query", c.Request.URL.RawQuery))
+		}
+
+		if handlerAttrs, ok := c.Get(attrsKey); ok {
+			attrs = append(attrs, handlerAttrs.([]slog.Attr)...)
+		}
+
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		} else if status >= http.StatusBadRequest {
+			level = slog.LevelWarn
+		}
+
+		slog.LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// AddAttrs adds attributes to the log message of the request
+func AddAttrs(c *gin.Context, attrs ...slog.Attr) {
+	if handlerAttrs, ok := c.Get(attrsKey); ok {
+		attrs = append(handlerAttrs.([]slog.Attr), attrs...)
+	}
+	c.Set(attrsKey, attrs)
+}
+
+func RequestId(c *gin.Context) string {
+	return c.GetString(requestIdKey)
+}
+
+// Logger returns the default logger tagged with the id of the request
+func Logger(c *gin.Context) *slog.Logger {
+	return slog.Default().With("requestId", RequestId(c))
+}
@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "sitelook"
+
+// Search results are labeled with one of these
+const (
+	ResultPage    = "page"
+	ResultCaptcha = "captcha"
+	ResultError   = "error"
+	ResultProxy   = "proxy_error"
+)
+
+var searchRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "search_requests_total",
+	Help:      "Searches by vertical, backend and result (page, captcha, error or proxy_error).",
+}, []string{"vertical", "backend", "result"})
+
+var searchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Name:      "search_duration_seconds",
+	Help:      "Duration of searches including the failover and cached results.",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"vertical"})
+
+var upstreamDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Name:      "upstream_request_duration_seconds",
+	Help:      "Duration of requests to backends, responses from the disk cache aren't counted.",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"backend"})
+
+var upstreamResponses = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "upstream_responses_total",
+	Help:      "Responses of backends by status code, `error` when the backend couldn't be reached.",
+}, []string{"backend", "status"})
+
+var captchas = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "captchas_total",
+	Help:      "Searches a backend answered with a captcha (429 Too Many Requests).",
+}, []string{"backend"})
+
+var cacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "cache_lookups_total",
+	Help:      "Result cache lookups by result (hit, miss or coalesced).",
+}, []string{"result"})
+
+var parseErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "parse_errors_total",
+	Help:      "Pages a parser returned an error for.",
+}, []string{"parser"})
+
+var parserEmptyRate = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "parser_empty_rate",
+	Help:      "Share of pages without results among the recent pages of a parser.",
+}, []string{"parser"})
+
+var parserBroken = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "parser_broken",
+	Help:      "1 while the breakage detector reports a parser as broken, 0 otherwise.",
+}, []string{"parser"})
+
+var proxyFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "proxy_failures_total",
+	Help:      "Backend requests failing because their outbound proxy couldn't be used.",
+}, []string{"proxy"})
+
+var imageProxyBytes = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "image_proxy_bytes_total",
+	Help:      "Bytes of images served through the image proxy.",
+})
+
+func ObserveSearch(vertical string, backend string, result string, duration time.Duration) {
+	searchRequests.WithLabelValues(vertical, backend, result).Inc()
+	searchDuration.WithLabelValues(vertical).Observe(duration.Seconds())
+}
+
+// ObserveUpstream counts a backend response, status is 0 when the backend
+// couldn't be reached
+func ObserveUpstream(backend string, status int, duration time.Duration) {
+	statusLabel := "error"
+	if status != 0 {
+		statusLabel = strconv.Itoa(status)
+	}
+
+	upstreamResponses.WithLabelValues(backend, statusLabel).Inc()
+	upstreamDuration.WithLabelValues(backend).Observe(duration.Seconds())
+}
+
+func CountCaptcha(backend string) {
+	captchas.WithLabelValues(backend).Inc()
+}
+
+func CountCacheLookup(result string) {
+	cacheLookups.WithLabelValues(result).Inc()
+}
+
+func CountParseError(parser string) {
+	parseErrors.WithLabelValues(parser).Inc()
+}
+
+// SetParserHealth exports the state of the breakage detector of a parser
+func SetParserHealth(parser string, emptyRate float64, broken bool) {
+	parserEmptyRate.WithLabelValues(parser).Set(emptyRate)
+
+	if broken {
+		parserBroken.WithLabelValues(parser).Set(1)
+	} else {
+		parserBroken.WithLabelValues(parser).Set(0)
+	}
+}
+
+// CountProxyFailure counts a failed request of an outbound proxy, labeled
+// with its host:port so credentials in proxy urls aren't exposed
+func CountProxyFailure(proxy string) {
+	proxyFailures.WithLabelValues(proxy).Inc()
+}
+
+func AddImageProxyBytes(bytes int64) {
+	imageProxyBytes.Add(float64(bytes))
+}
+
+// Route serves the metrics along with the runtime's ones in the Prometheus
+// text format
+func Route(c *gin.Context) {
+	promhttp.Handler().ServeHTTP(c.Writer, c.Request)
+}
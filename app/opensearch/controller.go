@@ -0,0 +1,119 @@
+package opensearch
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const descriptionContentType = "application/opensearchdescription+xml"
+
+type image struct {
+	Width  int    `xml:"width,attr"`
+	Height int    `xml:"height,attr"`
+	Type   string `xml:"type,attr"`
+	Url    string `xml:",chardata"`
+}
+
+type urlTemplate struct {
+	Type     string `xml:"type,attr"`
+	Method   string `xml:"method,attr,omitempty"`
+	Rel      string `xml:"rel,attr,omitempty"`
+	Template string `xml:"template,attr"`
+}
+
+type description struct {
+	XMLName       xml.Name      `xml:"http://a9.com/-/spec/opensearch/1.1/ OpenSearchDescription"`
+	ShortName     string        `xml:"ShortName"`
+	Description   string        `xml:"Description"`
+	InputEncoding string        `xml:"InputEncoding"`
+	Image         image         `xml:"Image"`
+	Urls          []urlTemplate `xml:"Url"`
+}
+
+// Base url of the instance e.g. `https://sitelook.example.com`. When it is
+// not set, the url is derived from the request.
+var baseUrl = ""
+
+func SetBaseUrl(url string) {
+	baseUrl = strings.TrimSuffix(url, "/")
+}
+
+// Request hosts are only used when they are a host name or ip address with
+// an optional port
+var hostPattern = regexp.MustCompile(`^([A-Za-z0-9.-]+|\[[0-9A-Fa-f:.]+\])(:[0-9]+)?$`)
+
+// getBaseUrl derives the base url from the request when it isn't set. The
+// Host and X-Forwarded-Proto headers come from the client, so only schemes
+// and hosts which can be used in the templates are accepted.
+func getBaseUrl(c *gin.Context) (string, error) {
+	if len(baseUrl) > 0 {
+		return baseUrl, nil
+	}
+
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+
+	// a chain of proxies lists the scheme of the first one first
+	if forwardedProto := c.GetHeader("X-Forwarded-Proto"); len(forwardedProto) > 0 {
+		forwardedProto, _, _ = strings.Cut(forwardedProto, ",")
+		forwardedProto = strings.ToLower(strings.TrimSpace(forwardedProto))
+
+		if forwardedProto != "http" && forwardedProto != "https" {
+			return "", fmt.Errorf("unsupported X-Forwarded-Proto %q", forwardedProto)
+		}
+		scheme = forwardedProto
+	}
+
+	if !hostPattern.MatchString(c.Request.Host) {
+		return "", fmt.Errorf("unsupported host %q", c.Request.Host)
+	}
+
+	return scheme + "://" + c.Request.Host, nil
+}
+
+func createDescription(baseUrl string) description {
+	return description{
+		ShortName:     "sitelook",
+		Description:   "Search with sitelook",
+		InputEncoding: "UTF-8",
+		Image: image{
+			Width:  32,
+			Height: 32,
+			Type:   "image/png",
+			Url:    baseUrl + "/static/icons/favicon.png",
+		},
+		Urls: []urlTemplate{
+			{Type: "text/html", Method: "get", Template: baseUrl + "/search?q={searchTerms}"},
+			{Type: "application/x-suggestions+json", Method: "get", Template: baseUrl + "/suggest?q={searchTerms}"},
+			{Type: descriptionContentType, Rel: "self", Template: baseUrl + "/opensearch.xml"},
+		},
+	}
+}
+
+func DescriptionRoute(c *gin.Context) {
+	requestBaseUrl, err := getBaseUrl(c)
+	if err != nil {
+		c.String(http.StatusBadRequest, "%s, set the base url of the instance", err)
+		return
+	}
+
+	// descriptions derived from requests differ by their headers
+	if len(baseUrl) == 0 {
+		c.Header("Vary", "Host, X-Forwarded-Proto")
+	}
+
+	body, err := xml.MarshalIndent(createDescription(requestBaseUrl), "", "    ")
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.Data(http.StatusOK, descriptionContentType+"; charset=utf-8", append([]byte(xml.Header), body...))
+}
@@ -0,0 +1,125 @@
+package opensearch
+
+import (
+	"crypto/tls"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func serveDescription(t *testing.T, configuredBaseUrl string, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+
+	SetBaseUrl(configuredBaseUrl)
+	t.Cleanup(func() { SetBaseUrl("") })
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/opensearch.xml", DescriptionRoute)
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestDescriptionRoute(t *testing.T) {
+	tests := []struct {
+		name        string
+		baseUrl     string
+		host        string
+		tls         bool
+		proto       string
+		wantBaseUrl string
+	}{
+		{name: "configured base url", baseUrl: "https://sitelook.example.com/", host: "internal:8080", wantBaseUrl: "https://sitelook.example.com"},
+		{name: "request host", host: "localhost:8080", wantBaseUrl: "http://localhost:8080"},
+		{name: "tls", host: "sitelook.example.com", tls: true, wantBaseUrl: "https://sitelook.example.com"},
+		{name: "forwarded proto", host: "sitelook.example.com", proto: "HTTPS", wantBaseUrl: "https://sitelook.example.com"},
+		{name: "forwarded by several proxies", host: "sitelook.example.com", proto: "https, http", wantBaseUrl: "https://sitelook.example.com"},
+		{name: "ipv6 host", host: "[::1]:8080", wantBaseUrl: "http://[::1]:8080"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/opensearch.xml", nil)
+			req.Host = test.host
+			if test.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			if len(test.proto) > 0 {
+				req.Header.Set("X-Forwarded-Proto", test.proto)
+			}
+
+			recorder := serveDescription(t, test.baseUrl, req)
+
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("got status %d: %s", recorder.Code, recorder.Body)
+			}
+			if got := recorder.Header().Get("Content-Type"); got != descriptionContentType+"; charset=utf-8" {
+				t.Errorf("got content type %q", got)
+			}
+
+			got := description{}
+			if err := xml.Unmarshal(recorder.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+
+			if got.XMLName.Space != "http://a9.com/-/spec/opensearch/1.1/" {
+				t.Errorf("got namespace %q", got.XMLName.Space)
+			}
+
+			want := createDescription(test.wantBaseUrl)
+			want.XMLName = got.XMLName
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v\nwant %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestDescriptionRouteTemplates(t *testing.T) {
+	templates := map[string]string{}
+	for _, url := range createDescription("https://sitelook.example.com").Urls {
+		templates[url.Type] = url.Template
+	}
+
+	want := map[string]string{
+		"text/html":                      "https://sitelook.example.com/search?q={searchTerms}",
+		"application/x-suggestions+json": "https://sitelook.example.com/suggest?q={searchTerms}",
+		descriptionContentType:           "https://sitelook.example.com/opensearch.xml",
+	}
+
+	if !reflect.DeepEqual(templates, want) {
+		t.Errorf("got templates %v, want %v", templates, want)
+	}
+}
+
+func TestDescriptionRouteRefusesUnsupportedHeaders(t *testing.T) {
+	tests := []struct {
+		name  string
+		host  string
+		proto string
+	}{
+		{name: "forwarded proto", host: "sitelook.example.com", proto: "javascript"},
+		{name: "host with a path", host: "sitelook.example.com/search?q=", proto: ""},
+		{name: "host with credentials", host: "user@sitelook.example.com", proto: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/opensearch.xml", nil)
+			req.Host = test.host
+			if len(test.proto) > 0 {
+				req.Header.Set("X-Forwarded-Proto", test.proto)
+			}
+
+			if recorder := serveDescription(t, "", req); recorder.Code != http.StatusBadRequest {
+				t.Errorf("got status %d, want %d", recorder.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
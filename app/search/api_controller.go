@@ -0,0 +1,148 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ApiSearchRoute(c *gin.Context) {
+	searchTerm := c.Query("q")
+	queryParams := createSearchQueryParams(c)
+	queryParams.Type = ""
+	query := createApiQuery(searchTerm, queryParams)
+
+	if !validateApiRequest(c, searchTerm, queryParams, query) {
+		return
+	}
+
+	searchResponse, err := Search(c.Request.Context(), searchTerm, queryParams)
+	setCacheHeader(c, searchResponse.Cached)
+	logSearch(c, searchResponse.Backend, countSearchResults(searchResponse.SearchPage), searchResponse.Cached, err)
+
+	if searchResponse.Type == SearchResponsePage && searchResponse.SearchPage != nil {
+		c.JSON(http.StatusOK, createApiSearchResponse(*searchResponse.SearchPage, searchResponse.Backend, query))
+		return
+	}
+
+	status, apiError := createApiError(searchResponse.Type, searchResponse.Status, searchResponse.Backend, searchResponse.Captcha, err)
+	c.JSON(status, createApiErrorResponse(apiError, query))
+}
+
+func ApiImagesRoute(c *gin.Context) {
+	searchTerm := c.Query("q")
+	queryParams := createSearchQueryParams(c)
+	queryParams.Type = "isch"
+	query := createApiQuery(searchTerm, queryParams)
+
+	if !validateApiRequest(c, searchTerm, queryParams, query) {
+		return
+	}
+
+	searchResponse, err := ImageSearch(c.Request.Context(), searchTerm, queryParams)
+	setCacheHeader(c, searchResponse.Cached)
+	logSearch(c, searchResponse.Backend, countImageResults(searchResponse.ImagesPage), searchResponse.Cached, err)
+
+	if searchResponse.Type == SearchResponsePage && searchResponse.ImagesPage != nil {
+		c.JSON(http.StatusOK, createApiImagesResponse(*searchResponse.ImagesPage, searchResponse.Backend, query))
+		return
+	}
+
+	status, apiError := createApiError(searchResponse.Type, searchResponse.Status, searchResponse.Backend, searchResponse.Captcha, err)
+	c.JSON(status, createApiErrorResponse(apiError, query))
+}
+
+func ApiVideosRoute(c *gin.Context) {
+	searchTerm := c.Query("q")
+	queryParams := createSearchQueryParams(c)
+	queryParams.Type = "vid"
+	query := createApiQuery(searchTerm, queryParams)
+
+	if !validateApiRequest(c, searchTerm, queryParams, query) {
+		return
+	}
+
+	searchResponse, err := VideoSearch(c.Request.Context(), searchTerm, queryParams)
+	setCacheHeader(c, searchResponse.Cached)
+	logSearch(c, searchResponse.Backend, countVideoResults(searchResponse.VideosPage), searchResponse.Cached, err)
+
+	if searchResponse.Type == SearchResponsePage && searchResponse.VideosPage != nil {
+		c.JSON(http.StatusOK, createApiVideosResponse(*searchResponse.VideosPage, searchResponse.Backend, query))
+		return
+	}
+
+	status, apiError := createApiError(searchResponse.Type, searchResponse.Status, searchResponse.Backend, searchResponse.Captcha, err)
+	c.JSON(status, createApiErrorResponse(apiError, query))
+}
+
+func validateApiRequest(c *gin.Context, searchTerm string, queryParams SearchQueryParams, query ApiQuery) bool {
+	if len(searchTerm) == 0 {
+		apiError := ApiError{Code: ApiErrorBadRequest, Message: "empty search term"}
+		c.JSON(http.StatusBadRequest, createApiErrorResponse(apiError, query))
+		return false
+	}
+
+	if _, err := getBackend(queryParams.Backend); err != nil {
+		apiError := ApiError{Code: ApiErrorBadRequest, Message: err.Error()}
+		c.JSON(http.StatusBadRequest, createApiErrorResponse(apiError, query))
+		return false
+	}
+
+	return true
+}
+
+func createApiError(responseType int, upstreamStatus int, backendName string, captcha *CaptchaPage, err error) (int, ApiError) {
+	if errors.Is(err, ErrSearchTypeNotSupported) {
+		return http.StatusNotImplemented, ApiError{
+			Code:    ApiErrorNotSupported,
+			Message: err.Error(),
+			Backend: backendName,
+		}
+	}
+
+	if responseType == SearchResponseCaptcha || errors.Is(err, ErrCaptcha) {
+		apiError := ApiError{
+			Code:           ApiErrorCaptcha,
+			Message:        "backend required captcha for this request",
+			Backend:        backendName,
+			UpstreamStatus: upstreamStatus,
+		}
+
+		if captcha != nil {
+			apiError.RedirectUrl = captcha.SearchUrl
+		}
+
+		return http.StatusServiceUnavailable, apiError
+	}
+
+	if responseType == SearchResponseProxyError || errors.Is(err, ErrProxy) {
+		return http.StatusBadGateway, ApiError{
+			Code:    ApiErrorProxy,
+			Message: "backend couldn't be reached through the outbound proxies",
+			Backend: backendName,
+		}
+	}
+
+	if errors.Is(err, ErrUpstreamStatus) || (upstreamStatus != 0 && upstreamStatus != http.StatusOK) {
+		return http.StatusBadGateway, ApiError{
+			Code:           ApiErrorUpstreamStatus,
+			Message:        fmt.Sprintf("backend responded with status %d", upstreamStatus),
+			Backend:        backendName,
+			UpstreamStatus: upstreamStatus,
+		}
+	}
+
+	message := "unknown error"
+	if err != nil {
+		message = err.Error()
+	}
+
+	return http.StatusBadGateway, ApiError{
+		Code:           ApiErrorUpstream,
+		Message:        message,
+		Backend:        backendName,
+		UpstreamStatus: upstreamStatus,
+	}
+}
@@ -0,0 +1,172 @@
+package search
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serveApi runs an api request against a stand-in backend, with a fresh
+// page cache and circuit breakers
+func serveApi(t *testing.T, backend *fixtureBackend, path string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	backend.name = "stand-in"
+	RegisterBackend(backend)
+	t.Cleanup(func() { delete(backends, backend.name) })
+
+	resetBreakers(t, 3, time.Minute)
+
+	previousCache := pageCache
+	SetCache(0, time.Minute)
+	t.Cleanup(func() { pageCache = previousCache })
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/api/search", ApiSearchRoute)
+	engine.GET("/api/images", ApiImagesRoute)
+	engine.GET("/api/videos", ApiVideosRoute)
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path+"&backend=stand-in", nil))
+	return recorder
+}
+
+func TestApiRoutes(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		fixture     string
+		status      int
+		wantStatus  int
+		wantResults bool
+		wantError   string
+	}{
+		{name: "search", path: "/api/search?q=golang", fixture: "google/search/first-page.html", wantStatus: http.StatusOK, wantResults: true},
+		{name: "search without results", path: "/api/search?q=qwxzvbnmlkjhgf", fixture: "google/search/no-results.html", wantStatus: http.StatusOK},
+		{name: "search captcha", path: "/api/search?q=golang", fixture: "google/search/captcha.html", status: http.StatusTooManyRequests, wantStatus: http.StatusServiceUnavailable, wantError: ApiErrorCaptcha},
+		{name: "search captcha page", path: "/api/search?q=golang", fixture: "google/search/captcha.html", wantStatus: http.StatusServiceUnavailable, wantError: ApiErrorCaptcha},
+		{name: "images captcha page", path: "/api/images?q=cats", fixture: "google/search/captcha.html", wantStatus: http.StatusServiceUnavailable, wantError: ApiErrorCaptcha},
+		{name: "images", path: "/api/images?q=cats", fixture: "google/images/first-page.html", wantStatus: http.StatusOK, wantResults: true},
+		{name: "images without results", path: "/api/images?q=qwxzvbnmlkjhgf", fixture: "google/images/no-results.html", wantStatus: http.StatusOK},
+		{name: "images parse error", path: "/api/images?q=cats", fixture: "google/images/not-a-results-page.html", wantStatus: http.StatusBadGateway, wantError: ApiErrorUpstream},
+		{name: "images upstream status", path: "/api/images?q=cats", fixture: "google/images/no-results.html", status: http.StatusServiceUnavailable, wantStatus: http.StatusBadGateway, wantError: ApiErrorUpstreamStatus},
+		{name: "videos", path: "/api/videos?q=cats", fixture: "google/videos/first-page.html", wantStatus: http.StatusOK, wantResults: true},
+		{name: "videos without results", path: "/api/videos?q=qwxzvbnmlkjhgf", fixture: "google/videos/no-results.html", wantStatus: http.StatusOK},
+		{name: "videos parse error", path: "/api/videos?q=cats", fixture: "google/videos/not-a-results-page.html", wantStatus: http.StatusBadGateway, wantError: ApiErrorUpstream},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := serveApi(t, &fixtureBackend{fixture: test.fixture, status: test.status}, test.path)
+
+			if recorder.Code != test.wantStatus {
+				t.Fatalf("got status %d, want %d: %s", recorder.Code, test.wantStatus, recorder.Body)
+			}
+
+			var response struct {
+				Results []json.RawMessage `json:"results"`
+				Error   *ApiError         `json:"error"`
+			}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(test.wantError) == 0 {
+				if response.Error != nil {
+					t.Fatalf("got error %+v, want results", *response.Error)
+				}
+				if response.Results == nil || (len(response.Results) > 0) != test.wantResults {
+					t.Errorf("got %d results, want results %t", len(response.Results), test.wantResults)
+				}
+				return
+			}
+
+			if response.Error == nil || response.Error.Code != test.wantError {
+				t.Fatalf("got error %+v, want code %s", response.Error, test.wantError)
+			}
+			if response.Results != nil {
+				t.Errorf("error response has results")
+			}
+		})
+	}
+}
+
+func TestCreateApiError(t *testing.T) {
+	backend := &GoogleBackend{}
+	captcha := &CaptchaPage{SearchUrl: "https://www.google.com/search?q=golang"}
+
+	tests := []struct {
+		name           string
+		responseType   int
+		upstreamStatus int
+		captcha        *CaptchaPage
+		err            error
+		wantStatus     int
+		wantError      ApiError
+	}{
+		{
+			name:         "not supported",
+			responseType: SearchResponseError,
+			err:          ErrSearchTypeNotSupported,
+			wantStatus:   http.StatusNotImplemented,
+			wantError:    ApiError{Code: ApiErrorNotSupported, Message: ErrSearchTypeNotSupported.Error(), Backend: "google"},
+		},
+		{
+			name:           "captcha",
+			responseType:   SearchResponseCaptcha,
+			upstreamStatus: http.StatusTooManyRequests,
+			captcha:        captcha,
+			err:            newSearchError(ErrCaptcha, backend, "", http.StatusTooManyRequests, nil),
+			wantStatus:     http.StatusServiceUnavailable,
+			wantError:      ApiError{Code: ApiErrorCaptcha, Message: "backend required captcha for this request", Backend: "google", UpstreamStatus: http.StatusTooManyRequests, RedirectUrl: captcha.SearchUrl},
+		},
+		{
+			name:         "proxy",
+			responseType: SearchResponseProxyError,
+			err:          newSearchError(ErrProxy, backend, "", 0, errors.New("connection refused")),
+			wantStatus:   http.StatusBadGateway,
+			wantError:    ApiError{Code: ApiErrorProxy, Message: "backend couldn't be reached through the outbound proxies", Backend: "google"},
+		},
+		{
+			name:           "upstream status",
+			responseType:   SearchResponseError,
+			upstreamStatus: http.StatusServiceUnavailable,
+			err:            newSearchError(ErrUpstreamStatus, backend, "", http.StatusServiceUnavailable, nil),
+			wantStatus:     http.StatusBadGateway,
+			wantError:      ApiError{Code: ApiErrorUpstreamStatus, Message: "backend responded with status 503", Backend: "google", UpstreamStatus: http.StatusServiceUnavailable},
+		},
+		{
+			name:           "parse error",
+			responseType:   SearchResponseError,
+			upstreamStatus: http.StatusOK,
+			err:            errors.New("search input not found"),
+			wantStatus:     http.StatusBadGateway,
+			wantError:      ApiError{Code: ApiErrorUpstream, Message: "search input not found", Backend: "google", UpstreamStatus: http.StatusOK},
+		},
+		{
+			name:         "unknown",
+			responseType: SearchResponseError,
+			wantStatus:   http.StatusBadGateway,
+			wantError:    ApiError{Code: ApiErrorUpstream, Message: "unknown error", Backend: "google"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status, apiError := createApiError(test.responseType, test.upstreamStatus, "google", test.captcha, test.err)
+
+			if status != test.wantStatus {
+				t.Errorf("got status %d, want %d", status, test.wantStatus)
+			}
+			if apiError != test.wantError {
+				t.Errorf("got %+v, want %+v", apiError, test.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package search
+
+// ApiVersion is increased on every incompatible change of the api responses
+const ApiVersion = 1
+
+const (
+	ApiErrorBadRequest     = "bad_request"
+	ApiErrorNotSupported   = "not_supported"
+	ApiErrorCaptcha        = "captcha"
+	ApiErrorUpstream       = "upstream_error"
+	ApiErrorUpstreamStatus = "upstream_status"
+	ApiErrorProxy          = "proxy_error"
+)
+
+type ApiQuery struct {
+	SearchTerm        string `json:"q"`
+	Start             int    `json:"start"`
+	Type              string `json:"type"`
+	SearchLanguage    string `json:"lr,omitempty"`
+	InterfaceLanguage string `json:"hl,omitempty"`
+	Backend           string `json:"backend"`
+}
+
+type ApiPagination struct {
+	HasPrevious   bool   `json:"has_previous"`
+	PreviousStart int    `json:"previous_start"`
+	HasNext       bool   `json:"has_next"`
+	NextStart     int    `json:"next_start"`
+	CurrentTitle  string `json:"current_title"`
+}
+
+type ApiCorrection struct {
+	Title      string `json:"title"`
+	SearchTerm string `json:"q"`
+}
+
+type ApiSearchResult struct {
+	Url         string   `json:"url"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Engines     []string `json:"engines,omitempty"`
+}
+
+type ApiImageResult struct {
+	Title        string `json:"title"`
+	Source       string `json:"source"`
+	ThumbnailUrl string `json:"thumbnail_url"`
+	PageUrl      string `json:"page_url"`
+	ImageUrl     string `json:"image_url"`
+}
+
+type ApiVideoResult struct {
+	Title        string `json:"title"`
+	Url          string `json:"url"`
+	ThumbnailUrl string `json:"thumbnail_url"`
+	Description  string `json:"description"`
+}
+
+type ApiError struct {
+	Code           string `json:"code"`
+	Message        string `json:"message"`
+	Backend        string `json:"backend,omitempty"`
+	UpstreamStatus int    `json:"upstream_status,omitempty"`
+	RedirectUrl    string `json:"redirect_url,omitempty"`
+}
+
+type ApiErrorResponse struct {
+	ApiVersion int      `json:"api_version"`
+	Query      ApiQuery `json:"query"`
+	Error      ApiError `json:"error"`
+}
+
+type ApiSearchResponse struct {
+	ApiVersion  int               `json:"api_version"`
+	Query       ApiQuery          `json:"query"`
+	Backend     string            `json:"backend"`
+	Results     []ApiSearchResult `json:"results"`
+	Pagination  ApiPagination     `json:"pagination"`
+	Correction  *ApiCorrection    `json:"correction"`
+	Answers     []string          `json:"answers"`
+	Suggestions []string          `json:"suggestions"`
+}
+
+type ApiImagesResponse struct {
+	ApiVersion int              `json:"api_version"`
+	Query      ApiQuery         `json:"query"`
+	Backend    string           `json:"backend"`
+	Results    []ApiImageResult `json:"results"`
+	Pagination ApiPagination    `json:"pagination"`
+}
+
+type ApiVideosResponse struct {
+	ApiVersion int              `json:"api_version"`
+	Query      ApiQuery         `json:"query"`
+	Backend    string           `json:"backend"`
+	Results    []ApiVideoResult `json:"results"`
+	Pagination ApiPagination    `json:"pagination"`
+}
+
+func createApiQuery(searchTerm string, params SearchQueryParams) ApiQuery {
+	return ApiQuery{
+		SearchTerm:        searchTerm,
+		Start:             params.Start,
+		Type:              params.Type,
+		SearchLanguage:    params.SearchLanguage,
+		InterfaceLanguage: params.InterfaceLanguage,
+		Backend:           resolveBackendName(params.Backend, params.Type),
+	}
+}
+
+func createApiPagination(pagination SinglePagePagination) ApiPagination {
+	return ApiPagination{
+		HasPrevious:   pagination.PreviousLinkPresent,
+		PreviousStart: pagination.PreviousOffset,
+		HasNext:       pagination.NextLinkPresent,
+		NextStart:     pagination.NextOffset,
+		CurrentTitle:  pagination.CurrentTitle,
+	}
+}
+
+func createApiCorrection(searchCorrection SearchCorrection) *ApiCorrection {
+	if !searchCorrection.Present {
+		return nil
+	}
+
+	return &ApiCorrection{
+		Title:      searchCorrection.Title,
+		SearchTerm: searchCorrection.CorrectSearchTerm,
+	}
+}
+
+func createApiSearchResponse(searchPage SearchPage, backendName string, query ApiQuery) ApiSearchResponse {
+	results := make([]ApiSearchResult, len(searchPage.SearchResults))
+
+	for i := 0; i < len(searchPage.SearchResults); i++ {
+		result := searchPage.SearchResults[i]
+		results[i] = ApiSearchResult{
+			Url:         result.Url,
+			Title:       result.Title,
+			Description: result.Description,
+			Engines:     result.Engines,
+		}
+	}
+
+	return ApiSearchResponse{
+		ApiVersion:  ApiVersion,
+		Query:       query,
+		Backend:     backendName,
+		Results:     results,
+		Pagination:  createApiPagination(searchPage.Pagination),
+		Correction:  createApiCorrection(searchPage.SearchCorrection),
+		Answers:     nonNilStrings(searchPage.Answers),
+		Suggestions: nonNilStrings(searchPage.Suggestions),
+	}
+}
+
+func createApiImagesResponse(imagesPage ImagesPage, backendName string, query ApiQuery) ApiImagesResponse {
+	results := make([]ApiImageResult, len(imagesPage.ImageResults))
+
+	for i := 0; i < len(imagesPage.ImageResults); i++ {
+		result := imagesPage.ImageResults[i]
+		results[i] = ApiImageResult{
+			Title:        result.Title,
+			Source:       result.UrlTitle,
+			ThumbnailUrl: result.ImageSrc,
+			PageUrl:      result.TitleLinkHref,
+			ImageUrl:     result.ImageLinkHref,
+		}
+	}
+
+	return ApiImagesResponse{
+		ApiVersion: ApiVersion,
+		Query:      query,
+		Backend:    backendName,
+		Results:    results,
+		Pagination: createApiPagination(imagesPage.Pagination),
+	}
+}
+
+func createApiVideosResponse(videosPage VideosPage, backendName string, query ApiQuery) ApiVideosResponse {
+	results := make([]ApiVideoResult, len(videosPage.VideoResults))
+
+	for i := 0; i < len(videosPage.VideoResults); i++ {
+		result := videosPage.VideoResults[i]
+		results[i] = ApiVideoResult{
+			Title:        result.Title,
+			Url:          result.TitleLinkHref,
+			ThumbnailUrl: result.ImageSrc,
+			Description:  result.Description,
+		}
+	}
+
+	return ApiVideosResponse{
+		ApiVersion: ApiVersion,
+		Query:      query,
+		Backend:    backendName,
+		Results:    results,
+		Pagination: createApiPagination(videosPage.Pagination),
+	}
+}
+
+func createApiErrorResponse(apiError ApiError, query ApiQuery) ApiErrorResponse {
+	return ApiErrorResponse{
+		ApiVersion: ApiVersion,
+		Query:      query,
+		Error:      apiError,
+	}
+}
+
+// nil slices are encoded as `null`, the api always returns arrays
+func nonNilStrings(items []string) []string {
+	if items == nil {
+		return []string{}
+	}
+	return items
+}
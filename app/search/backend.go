@@ -0,0 +1,104 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Backend is a search engine sitelook can forward queries to. A backend builds
+// the upstream url for a query, fetches it and parses the response into the
+// pages rendered by the templates.
+type Backend interface {
+	Name() string
+	Title() string
+	SearchUrl(searchTerm string, searchType string, params SearchQueryParams) (string, error)
+	Fetch(ctx context.Context, searchUrl string) (body []byte, err error, status int)
+	ParseSearchPage(body []byte, params SearchQueryParams) (*SearchPage, error)
+	ParseImagesPage(body []byte, params SearchQueryParams) (ImagesPage, error)
+	ParseVideosPage(body []byte, params SearchQueryParams) (VideosPage, error)
+}
+
+var ErrSearchTypeNotSupported = errors.New("search type is not supported by the backend")
+
+var backends = map[string]Backend{}
+var defaultBackendName = ""
+
+func init() {
+	RegisterBackend(&GoogleBackend{})
+	RegisterBackend(&DuckDuckGoBackend{})
+	RegisterBackend(&BingBackend{})
+	defaultBackendName = GoogleBackendName
+}
+
+// RegisterBackend makes the backend selectable by its name. A backend
+// registered under an existing name replaces the previous one.
+func RegisterBackend(backend Backend) {
+	backends[backend.Name()] = backend
+}
+
+// SetDefaultBackend selects the backend used when a request doesn't specify one.
+func SetDefaultBackend(name string) error {
+	if _, ok := backends[name]; !ok && name != MetasearchBackendName {
+		return fmt.Errorf("unknown backend %q (available: %v)", name, BackendNames())
+	}
+
+	defaultBackendName = name
+	return nil
+}
+
+func BackendNames() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func getBackend(name string) (Backend, error) {
+	if len(name) == 0 {
+		name = defaultBackendName
+	}
+
+	// only "All" search results are merged, images and videos are served
+	// by the first metasearch backend
+	if name == MetasearchBackendName {
+		name = metasearchBackendNames[0]
+	}
+
+	backend, ok := backends[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown backend %q", name)
+	}
+
+	return backend, nil
+}
+
+func backendTitle(name string) string {
+	if name == MetasearchBackendName {
+		return "Metasearch"
+	}
+
+	if backend, ok := backends[name]; ok {
+		return backend.Title()
+	}
+
+	return name
+}
+
+// resolveBackendName returns the name of the backend which is supposed to
+// serve the request when none of the backends fails
+func resolveBackendName(name string, searchType string) string {
+	if len(searchType) == 0 && isMetasearch(name) {
+		return MetasearchBackendName
+	}
+
+	backend, err := getBackend(name)
+	if err != nil {
+		return name
+	}
+
+	return backend.Name()
+}
@@ -0,0 +1,108 @@
+package search
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+const BingBackendName = "bing"
+
+// Number of results requested for images and videos. Bing loads these pages
+// with infinite scrolling so the offsets are computed instead of parsed.
+const bingMediaPageSize = 35
+
+type BingBackend struct{}
+
+func (b *BingBackend) Name() string {
+	return BingBackendName
+}
+
+func (b *BingBackend) Title() string {
+	return "Bing"
+}
+
+func (b *BingBackend) SearchUrl(searchTerm string, searchType string, params SearchQueryParams) (string, error) {
+	path := ""
+
+	if len(searchType) == 0 {
+		path = "/search"
+	} else if searchType == "isch" {
+		path = "/images/search"
+	} else if searchType == "vid" {
+		path = "/videos/search"
+	} else {
+		return "", ErrSearchTypeNotSupported
+	}
+
+	return getBingSearchUrl(path, searchTerm, params.Start, len(searchType) > 0, params.InterfaceLanguage), nil
+}
+
+func (b *BingBackend) Fetch(ctx context.Context, searchUrl string) (body []byte, err error, status int) {
+	return getDocument(ctx, b.Name(), searchUrl, nil)
+}
+
+func (b *BingBackend) ParseSearchPage(body []byte, params SearchQueryParams) (*SearchPage, error) {
+	document, err := newDocument(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseBingSearchPage(document)
+}
+
+func (b *BingBackend) ParseImagesPage(body []byte, params SearchQueryParams) (ImagesPage, error) {
+	document, err := newDocument(body)
+	if err != nil {
+		return ImagesPage{}, err
+	}
+
+	return parseBingImagesPage(document, params.Start)
+}
+
+func (b *BingBackend) ParseVideosPage(body []byte, params SearchQueryParams) (VideosPage, error) {
+	document, err := newDocument(body)
+	if err != nil {
+		return VideosPage{}, err
+	}
+
+	return parseBingVideosPage(document, params.Start)
+}
+
+// Bing's `first` parameter is the 1-based index of the first result while
+// `start` is a 0-based offset
+func getBingSearchUrl(path string, searchTerm string, start int, media bool, interfaceLang string) string {
+	searchUrl, _ := url.Parse("https://www.bing.com" + path)
+	query := searchUrl.Query()
+
+	query.Add("q", searchTerm)
+
+	if start > 0 {
+		query.Add("first", strconv.Itoa(start+1))
+	}
+
+	if media {
+		query.Add("count", strconv.Itoa(bingMediaPageSize))
+	}
+
+	if len(interfaceLang) > 0 {
+		query.Add("setlang", interfaceLang)
+	}
+
+	searchUrl.RawQuery = query.Encode()
+	return searchUrl.String()
+}
+
+func (b *BingBackend) SuggestionsUrl(searchTerm string, params SearchQueryParams) string {
+	suggestionsUrl, _ := url.Parse("https://api.bing.com/osjson.aspx")
+	query := suggestionsUrl.Query()
+
+	query.Add("query", searchTerm)
+
+	if len(params.InterfaceLanguage) > 0 {
+		query.Add("setlang", params.InterfaceLanguage)
+	}
+
+	suggestionsUrl.RawQuery = query.Encode()
+	return suggestionsUrl.String()
+}
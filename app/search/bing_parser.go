@@ -0,0 +1,287 @@
+package search
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Metadata stored as json in the `m` attribute of image result links
+type bingImageMetadata struct {
+	ImageUrl     string `json:"murl"`
+	ThumbnailUrl string `json:"turl"`
+	PageUrl      string `json:"purl"`
+	Title        string `json:"t"`
+}
+
+// Metadata stored as json in the `vrhm` attribute of video results
+type bingVideoMetadata struct {
+	VideoUrl string `json:"murl"`
+	Title    string `json:"vt"`
+	Duration string `json:"du"`
+}
+
+// bingRedirectTarget extracts the result url from tracking links like
+// `https://www.bing.com/ck/a?!&&p=...&u=a1[base64 encoded result url]`
+func bingRedirectTarget(href string) string {
+	hrefUrl, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+
+	if hrefUrl.Host != "www.bing.com" || hrefUrl.Path != "/ck/a" {
+		return href
+	}
+
+	encodedUrl := strings.TrimPrefix(hrefUrl.Query().Get("u"), "a1")
+	decodedUrl, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encodedUrl, "="))
+	if err != nil {
+		return href
+	}
+
+	return string(decodedUrl)
+}
+
+func getBingOffsetFromHref(href string) (offset int, isSet bool) {
+	hrefUrl, err := url.Parse(href)
+	if err != nil {
+		return 0, false
+	}
+
+	first, err := strconv.Atoi(hrefUrl.Query().Get("first"))
+	if err != nil || first < 1 {
+		return 0, false
+	}
+
+	return first - 1, true
+}
+
+func parseBingSearchResults(document *goquery.Document) []SearchResult {
+	results := []SearchResult{}
+
+	document.Find("#b_results > li.b_algo").Each(func(i int, searchItem *goquery.Selection) {
+		titleLink := findSingle(searchItem, "h2 a")
+		if selectionEmpty(titleLink) {
+			return
+		}
+
+		description := strings.TrimSpace(findSingle(searchItem, ".b_caption p").Text())
+
+		results = append(results, SearchResult{
+			Url:         bingRedirectTarget(titleLink.AttrOr("href", "")),
+			Title:       strings.TrimSpace(titleLink.Text()),
+			Description: description,
+		})
+	})
+
+	return results
+}
+
+func parseBingPagination(document *goquery.Document) (SinglePagePagination, error) {
+	paginationList := findSingle(document.Selection, "#b_results .b_pag")
+
+	if selectionEmpty(paginationList) {
+		return SinglePagePagination{}, errors.New("pagination not found")
+	}
+
+	pagination := SinglePagePagination{
+		PreviousLinkPresent: false,
+		PreviousOffset:      0,
+		NextLinkPresent:     false,
+		NextOffset:          0,
+		CurrentTitle:        strings.TrimSpace(findSingle(paginationList, ".sb_pagS").Text()),
+	}
+
+	previousLink := findSingle(paginationList, "a.sb_pagP")
+	if !selectionEmpty(previousLink) {
+		// the first page link has no `first` parameter
+		pagination.PreviousOffset, _ = getBingOffsetFromHref(previousLink.AttrOr("href", "#"))
+		pagination.PreviousLinkPresent = true
+	}
+
+	nextLink := findSingle(paginationList, "a.sb_pagN")
+	if !selectionEmpty(nextLink) {
+		pagination.NextOffset, pagination.NextLinkPresent = getBingOffsetFromHref(nextLink.AttrOr("href", "#"))
+	}
+
+	return pagination, nil
+}
+
+func parseBingSearchCorrection(document *goquery.Document) SearchCorrection {
+	correctionContainer := findSingle(document.Selection, "#sp_requery")
+
+	correction := SearchCorrection{
+		Present: false,
+	}
+
+	correctionLink := findSingle(correctionContainer, "a")
+	if selectionEmpty(correctionLink) {
+		return correction
+	}
+
+	correctionUrl, err := url.Parse(correctionLink.AttrOr("href", ""))
+	if err != nil {
+		return correction
+	}
+
+	correction.Present = true
+	correction.Title = strings.TrimSpace(findSingle(correctionContainer, "span").Text())
+	correction.CorrectSearchTerm = correctionUrl.Query().Get("q")
+
+	return correction
+}
+
+func parseBingSearchInput(document *goquery.Document) (string, error) {
+	searchInput := findSingle(document.Selection, "input[name=\"q\"]")
+	if selectionEmpty(searchInput) {
+		return "", errors.New("search input not found")
+	}
+
+	return searchInput.AttrOr("value", ""), nil
+}
+
+func parseBingSearchPage(document *goquery.Document) (*SearchPage, error) {
+	searchTerm, err := parseBingSearchInput(document)
+	if err != nil {
+		return nil, err
+	}
+
+	pagination, _ := parseBingPagination(document)
+
+	searchPage := SearchPage{
+		SearchTerm:       searchTerm,
+		SearchResults:    parseBingSearchResults(document),
+		Pagination:       pagination,
+		SearchCorrection: parseBingSearchCorrection(document),
+	}
+
+	return &searchPage, nil
+}
+
+// Media pages are loaded with infinite scrolling, so the pagination is
+// derived from the requested offset and the page size
+func createBingMediaPagination(start int, resultCount int) SinglePagePagination {
+	pagination := SinglePagePagination{
+		PreviousLinkPresent: start > 0,
+		PreviousOffset:      0,
+		NextLinkPresent:     resultCount > 0,
+		NextOffset:          start + bingMediaPageSize,
+		CurrentTitle:        "",
+	}
+
+	if start > bingMediaPageSize {
+		pagination.PreviousOffset = start - bingMediaPageSize
+	}
+
+	if start > 0 {
+		pagination.CurrentTitle = strconv.Itoa(start/bingMediaPageSize + 1)
+	}
+
+	return pagination
+}
+
+func parseBingImagesPage(document *goquery.Document, start int) (ImagesPage, error) {
+	searchTerm, err := parseBingSearchInput(document)
+	if err != nil {
+		return ImagesPage{}, err
+	}
+
+	imageResults := make([]ImageResult, 0)
+
+	document.Find("a.iusc").Each(func(i int, link *goquery.Selection) {
+		metadata := bingImageMetadata{}
+		if err := json.Unmarshal([]byte(link.AttrOr("m", "")), &metadata); err != nil {
+			return
+		}
+
+		urlTitle := metadata.PageUrl
+		if pageUrl, err := url.Parse(metadata.PageUrl); err == nil {
+			urlTitle = strings.TrimPrefix(pageUrl.Host, "www.")
+		}
+
+		imageResults = append(imageResults, ImageResult{
+			Title:         metadata.Title,
+			UrlTitle:      urlTitle,
+			ImageSrc:      metadata.ThumbnailUrl,
+			TitleLinkHref: metadata.PageUrl,
+			ImageLinkHref: metadata.ImageUrl,
+		})
+	})
+
+	// a search which found nothing, see parseImagesPage
+	if len(imageResults) == 0 {
+		return ImagesPage{
+			SearchTerm:   searchTerm,
+			ImageResults: imageResults,
+		}, nil
+	}
+
+	return ImagesPage{
+		SearchTerm:   searchTerm,
+		ImageResults: imageResults,
+		Pagination:   createBingMediaPagination(start, len(imageResults)),
+	}, nil
+}
+
+func parseBingVideosPage(document *goquery.Document, start int) (VideosPage, error) {
+	searchTerm, err := parseBingSearchInput(document)
+	if err != nil {
+		return VideosPage{}, err
+	}
+
+	videoResults := make([]VideoResult, 0)
+
+	document.Find(".dg_u").Each(func(i int, item *goquery.Selection) {
+		metadata := bingVideoMetadata{}
+		if err := json.Unmarshal([]byte(findSingle(item, ".vrhdata").AttrOr("vrhm", "")), &metadata); err != nil {
+			return
+		}
+
+		img := findSingle(item, "img")
+		imgSrc := img.AttrOr("data-src-hq", img.AttrOr("src", ""))
+
+		descriptionParts := []string{}
+		if len(metadata.Duration) > 0 {
+			descriptionParts = append(descriptionParts, metadata.Duration)
+		}
+		item.Find(".mc_vtvc_meta_row").Each(func(i int, row *goquery.Selection) {
+			parts := row.Children()
+			if selectionEmpty(parts) {
+				parts = row
+			}
+
+			parts.Each(func(i int, part *goquery.Selection) {
+				if text := strings.TrimSpace(part.Text()); len(text) > 0 {
+					descriptionParts = append(descriptionParts, text)
+				}
+			})
+		})
+
+		videoResults = append(videoResults, VideoResult{
+			Title:         metadata.Title,
+			UrlTitle:      metadata.VideoUrl,
+			ImageSrc:      imgSrc,
+			TitleLinkHref: metadata.VideoUrl,
+			Description:   strings.Join(descriptionParts, " · "),
+		})
+	})
+
+	if len(videoResults) == 0 {
+		return VideosPage{
+			SearchTerm:   searchTerm,
+			VideoResults: videoResults,
+			Pagination:   SinglePagePagination{},
+		}, nil
+	}
+
+	return VideosPage{
+		SearchTerm:   searchTerm,
+		VideoResults: videoResults,
+		Pagination:   createBingMediaPagination(start, len(videoResults)),
+	}, nil
+}
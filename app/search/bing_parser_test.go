@@ -0,0 +1,179 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseBingSearchPage(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    SearchPage
+	}{
+		{
+			fixture: "bing/search-first-page.html",
+			want: SearchPage{
+				SearchTerm: "golang",
+				SearchResults: []SearchResult{
+					{
+						Url:         "https://go.dev/",
+						Title:       "The Go Programming Language",
+						Description: "Go is an open source programming language that makes it simple to build secure, scalable systems.",
+					},
+					{
+						Url:         "https://en.wikipedia.org/wiki/Go_(programming_language)",
+						Title:       "Go (programming language) - Wikipedia",
+						Description: "Go is a statically typed, compiled high-level programming language designed at Google.",
+					},
+					{
+						Url:         "https://github.com/golang/go",
+						Title:       "GitHub - golang/go: The Go programming language",
+						Description: "",
+					},
+				},
+				Pagination: SinglePagePagination{
+					NextLinkPresent: true,
+					NextOffset:      10,
+					CurrentTitle:    "1",
+				},
+			},
+		},
+		{
+			fixture: "bing/search-middle-page.html",
+			want: SearchPage{
+				SearchTerm: "golnag",
+				SearchResults: []SearchResult{
+					{
+						Url:         "https://gobyexample.com/",
+						Title:       "Go by Example",
+						Description: "Go by Example is a hands-on introduction to Go using annotated example programs.",
+					},
+				},
+				Pagination: SinglePagePagination{
+					PreviousLinkPresent: true,
+					PreviousOffset:      0,
+					NextLinkPresent:     true,
+					NextOffset:          20,
+					CurrentTitle:        "2",
+				},
+				SearchCorrection: SearchCorrection{
+					Present:           true,
+					Title:             "Including results for",
+					CorrectSearchTerm: "golang",
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.fixture, func(t *testing.T) {
+			searchPage, err := parseBingSearchPage(loadFixture(t, test.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(*searchPage, test.want) {
+				t.Errorf("got %+v, want %+v", *searchPage, test.want)
+			}
+		})
+	}
+}
+
+func TestParseBingImagesPage(t *testing.T) {
+	imagesPage, err := parseBingImagesPage(loadFixture(t, "bing/images.html"), 35)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := ImagesPage{
+		SearchTerm: "gopher",
+		ImageResults: []ImageResult{
+			{
+				Title:         "The Go Gopher - The Go Programming Language",
+				UrlTitle:      "go.dev",
+				ImageSrc:      "https://tse1.mm.bing.net/th?id=OIP.gopher1&pid=15.1",
+				TitleLinkHref: "https://go.dev/blog/gopher",
+				ImageLinkHref: "https://go.dev/blog/gopher/header.jpg",
+			},
+			{
+				Title:         "Gophers | National Geographic",
+				UrlTitle:      "nationalgeographic.com",
+				ImageSrc:      "https://tse2.mm.bing.net/th?id=OIP.gopher2&pid=15.1",
+				TitleLinkHref: "https://www.nationalgeographic.com/animals/mammals/facts/gophers",
+				ImageLinkHref: "https://i.natgeofe.com/n/gopher.jpg",
+			},
+		},
+		Pagination: SinglePagePagination{
+			PreviousLinkPresent: true,
+			PreviousOffset:      0,
+			NextLinkPresent:     true,
+			NextOffset:          70,
+			CurrentTitle:        "2",
+		},
+	}
+
+	if !reflect.DeepEqual(imagesPage, want) {
+		t.Errorf("got %+v, want %+v", imagesPage, want)
+	}
+}
+
+func TestParseBingVideosPage(t *testing.T) {
+	videosPage, err := parseBingVideosPage(loadFixture(t, "bing/videos.html"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := VideosPage{
+		SearchTerm: "golang tutorial",
+		VideoResults: []VideoResult{
+			{
+				Title:         "Go Programming – Golang Course with Bonus Projects",
+				UrlTitle:      "https://www.youtube.com/watch?v=un6ZyFkqFKo",
+				ImageSrc:      "https://tse1.mm.bing.net/th?id=OVP.video1&w=300&h=168",
+				TitleLinkHref: "https://www.youtube.com/watch?v=un6ZyFkqFKo",
+				Description:   "6:43:22 · 1.9M views · Jan 20, 2021 · freeCodeCamp.org",
+			},
+			{
+				Title:         "Learn Go Programming - Golang Tutorial for Beginners",
+				UrlTitle:      "https://www.youtube.com/watch?v=YS4e4q9oBaU",
+				ImageSrc:      "https://tse2.mm.bing.net/th?id=OVP.video2&w=300&h=168",
+				TitleLinkHref: "https://www.youtube.com/watch?v=YS4e4q9oBaU",
+				Description:   "3.1M views",
+			},
+		},
+		Pagination: SinglePagePagination{
+			NextLinkPresent: true,
+			NextOffset:      35,
+		},
+	}
+
+	if !reflect.DeepEqual(videosPage, want) {
+		t.Errorf("got %+v, want %+v", videosPage, want)
+	}
+}
+
+func TestGetBingSearchUrl(t *testing.T) {
+	backend := BingBackend{}
+
+	tests := []struct {
+		searchType string
+		start      int
+		want       string
+	}{
+		{searchType: "", start: 0, want: "https://www.bing.com/search?q=golang"},
+		{searchType: "", start: 10, want: "https://www.bing.com/search?first=11&q=golang"},
+		{searchType: "isch", start: 35, want: "https://www.bing.com/images/search?count=35&first=36&q=golang"},
+		{searchType: "vid", start: 0, want: "https://www.bing.com/videos/search?count=35&q=golang"},
+	}
+
+	for _, test := range tests {
+		got, err := backend.SearchUrl("golang", test.searchType, SearchQueryParams{Start: test.start})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != test.want {
+			t.Errorf("%q start %d: got %q, want %q", test.searchType, test.start, got, test.want)
+		}
+	}
+}
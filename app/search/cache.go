@@ -0,0 +1,194 @@
+package search
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"sitelook/app/metrics"
+)
+
+type cacheKey struct {
+	backend           string
+	searchTerm        string
+	start             int
+	searchType        string
+	searchLanguage    string
+	interfaceLanguage string
+}
+
+type cacheEntry struct {
+	key       cacheKey
+	value     interface{}
+	expiresAt time.Time
+}
+
+// cacheCall is an upstream request shared by identical concurrent searches
+type cacheCall struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Coalesced uint64
+	Entries   int
+}
+
+// resultCache is an LRU cache of parsed pages. Concurrent searches of the same
+// page wait for the first one instead of sending their own requests.
+type resultCache struct {
+	mutex    sync.Mutex
+	capacity int
+	ttl      time.Duration
+	entries  map[cacheKey]*list.Element
+	order    *list.List
+	calls    map[cacheKey]*cacheCall
+	stats    CacheStats
+}
+
+var pageCache = newResultCache(1000, 10*time.Minute)
+
+func newResultCache(capacity int, ttl time.Duration) *resultCache {
+	return &resultCache{
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  make(map[cacheKey]*list.Element),
+		order:    list.New(),
+		calls:    make(map[cacheKey]*cacheCall),
+	}
+}
+
+// SetCache sets the number of cached pages and how long they are kept.
+// A capacity of 0 turns the cache off, identical concurrent searches are
+// still coalesced.
+func SetCache(capacity int, ttl time.Duration) {
+	pageCache = newResultCache(capacity, ttl)
+}
+
+func GetCacheStats() CacheStats {
+	pageCache.mutex.Lock()
+	defer pageCache.mutex.Unlock()
+
+	stats := pageCache.stats
+	stats.Entries = pageCache.order.Len()
+	return stats
+}
+
+func createCacheKey(searchTerm string, searchType string, params SearchQueryParams) cacheKey {
+	return cacheKey{
+		backend:           resolveBackendName(params.Backend, searchType),
+		searchTerm:        searchTerm,
+		start:             params.Start,
+		searchType:        searchType,
+		searchLanguage:    params.SearchLanguage,
+		interfaceLanguage: params.InterfaceLanguage,
+	}
+}
+
+// lookup returns a cached value or the request in progress for the key.
+// When there is neither, the caller is registered as the one fetching it.
+func (c *resultCache) lookup(key cacheKey) (value interface{}, hit bool, call *cacheCall, isOwner bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*cacheEntry)
+		if time.Now().Before(entry.expiresAt) {
+			c.order.MoveToFront(element)
+			c.stats.Hits++
+			metrics.CountCacheLookup("hit")
+			return entry.value, true, nil, false
+		}
+
+		c.order.Remove(element)
+		delete(c.entries, key)
+	}
+
+	if call, ok := c.calls[key]; ok {
+		c.stats.Coalesced++
+		metrics.CountCacheLookup("coalesced")
+		return nil, false, call, false
+	}
+
+	c.stats.Misses++
+	metrics.CountCacheLookup("miss")
+	call = &cacheCall{done: make(chan struct{})}
+	c.calls[key] = call
+	return nil, false, call, true
+}
+
+func (c *resultCache) complete(key cacheKey, call *cacheCall, cacheable bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.calls, key)
+	close(call.done)
+
+	if !cacheable || c.capacity <= 0 {
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{
+		key:       key,
+		value:     call.value,
+		expiresAt: time.Now().Add(c.ttl),
+	})
+
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// cached returns the cached result of the search or runs it, sharing the
+// result with identical searches started in the meantime. Only results
+// accepted by isCacheable are stored.
+func cached[T any](ctx context.Context, key cacheKey, search func() (T, error), isCacheable func(T, error) bool) (result T, hit bool, err error) {
+	cache := pageCache
+	value, hit, call, isOwner := cache.lookup(key)
+
+	if hit {
+		return value.(T), true, nil
+	}
+
+	if !isOwner {
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return result, false, ctx.Err()
+		}
+
+		// the search was cancelled along with the request which started it,
+		// it's run again for this one
+		if errors.Is(call.err, context.Canceled) && ctx.Err() == nil {
+			return cached(ctx, key, search, isCacheable)
+		}
+
+		// a search which panicked leaves no value
+		value, _ := call.value.(T)
+		return value, false, call.err
+	}
+
+	// identical searches waiting for a search which panics get an error and
+	// the next one runs it again
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			call.err = fmt.Errorf("search failed: %v", recovered)
+			cache.complete(key, call, false)
+			panic(recovered)
+		}
+	}()
+
+	result, err = search()
+	call.value, call.err = result, err
+	cache.complete(key, call, isCacheable(result, err))
+
+	return result, false, err
+}
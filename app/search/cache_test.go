@@ -0,0 +1,185 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// useCache gives a test a fresh page cache and restores the previous one
+// afterwards
+func useCache(t *testing.T, capacity int) {
+	t.Helper()
+
+	previousCache := pageCache
+	SetCache(capacity, time.Minute)
+	t.Cleanup(func() { pageCache = previousCache })
+}
+
+func isPage(page string, err error) bool {
+	return err == nil
+}
+
+// waitForCoalesced waits until count searches wait for the one in progress
+func waitForCoalesced(t *testing.T, count uint64) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for GetCacheStats().Coalesced < count {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d coalesced searches, want %d", GetCacheStats().Coalesced, count)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCachedStoresCacheableResults(t *testing.T) {
+	useCache(t, 10)
+	key := cacheKey{searchTerm: "golang"}
+	searches := 0
+
+	search := func() (string, error) {
+		searches++
+		return "page", nil
+	}
+
+	if _, hit, _ := cached(context.Background(), key, search, isPage); hit {
+		t.Error("first search was a hit")
+	}
+
+	result, hit, err := cached(context.Background(), key, search, isPage)
+	if !hit || result != "page" || err != nil {
+		t.Errorf("got %q, hit %t, error %v, want the cached page", result, hit, err)
+	}
+
+	if searches != 1 {
+		t.Errorf("got %d searches, want 1", searches)
+	}
+}
+
+func TestCachedSkipsFailedSearches(t *testing.T) {
+	useCache(t, 10)
+	key := cacheKey{searchTerm: "golang"}
+	searches := 0
+
+	search := func() (string, error) {
+		searches++
+		return "", errors.New("captcha")
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, hit, err := cached(context.Background(), key, search, isPage); hit || err == nil {
+			t.Errorf("got hit %t, error %v, want the error", hit, err)
+		}
+	}
+
+	if searches != 2 {
+		t.Errorf("got %d searches, want 2", searches)
+	}
+}
+
+func TestCachedCoalescesConcurrentSearches(t *testing.T) {
+	useCache(t, 0)
+	key := cacheKey{searchTerm: "golang"}
+
+	const callers = 5
+	var searches atomic.Int32
+	release := make(chan struct{})
+
+	search := func() (string, error) {
+		searches.Add(1)
+		<-release
+		return "page", nil
+	}
+
+	results := make([]string, callers)
+	var wg sync.WaitGroup
+
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _, _ = cached(context.Background(), key, search, isPage)
+		}(i)
+	}
+
+	waitForCoalesced(t, callers-1)
+	close(release)
+	wg.Wait()
+
+	if searches.Load() != 1 {
+		t.Errorf("got %d searches, want 1", searches.Load())
+	}
+
+	for i, result := range results {
+		if result != "page" {
+			t.Errorf("caller %d got %q, want the shared page", i, result)
+		}
+	}
+}
+
+func TestCachedPanic(t *testing.T) {
+	useCache(t, 10)
+	key := cacheKey{searchTerm: "golang"}
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	panicked := make(chan interface{})
+	go func() {
+		defer func() { panicked <- recover() }()
+
+		cached(context.Background(), key, func() (string, error) {
+			close(started)
+			<-release
+			panic("parser bug")
+		}, isPage)
+	}()
+
+	<-started
+
+	waiterErr := make(chan error)
+	go func() {
+		_, _, err := cached(context.Background(), key, func() (string, error) {
+			t.Error("waiting search ran")
+			return "", nil
+		}, isPage)
+		waiterErr <- err
+	}()
+
+	waitForCoalesced(t, 1)
+	close(release)
+
+	if recovered := <-panicked; recovered != "parser bug" {
+		t.Errorf("got panic %v, want it passed on", recovered)
+	}
+
+	select {
+	case err := <-waiterErr:
+		if err == nil {
+			t.Error("waiting search got no error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("search waiting for a panicked one hangs")
+	}
+
+	// the panicked search isn't in progress anymore, the next one runs
+	done := make(chan string)
+	go func() {
+		result, _, _ := cached(context.Background(), key, func() (string, error) {
+			return "page", nil
+		}, isPage)
+		done <- result
+	}()
+
+	select {
+	case result := <-done:
+		if result != "page" {
+			t.Errorf("got %q, want the new page", result)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("search after a panic hangs")
+	}
+}
@@ -11,6 +11,7 @@ type SearchResultContext struct {
 	Title       string
 	UrlTitle    string
 	Description string
+	Engines     []string
 }
 
 type PageLinkContext struct {
@@ -32,6 +33,7 @@ type MultiPagePaginationContext struct {
 type SearchNavigationContext struct {
 	CurrentSearchType string
 	SearchQueryParam  string
+	BackendQueryParam string
 	AllSearchHref     string
 	ImageSearchHref   string
 	VideoSearchHref   string
@@ -44,16 +46,38 @@ type SearchCorrectionContext struct {
 	CorrectionHref    string
 }
 
+type SuggestionContext struct {
+	SearchTerm string
+	Href       string
+}
+
 type SearchPageContext struct {
 	SearchTerm       string
 	SearchResults    []SearchResultContext
 	Pagination       SinglePagePaginationContext
 	Navigation       SearchNavigationContext
+	Engine           EngineContext
+	Completions      []string
 	SearchCorrection SearchCorrectionContext
+	Answers          []string
+	Suggestions      []SuggestionContext
+}
+
+// EngineContext tells which backend answered the search, FallbackFromTitle
+// is set when the requested backend failed
+type EngineContext struct {
+	Title             string
+	FallbackFromTitle string
 }
 
 type CaptchaPageContext struct {
 	SearchRedirectUrl string
+	BackendTitle      string
+}
+
+type ErrorPageContext struct {
+	Title   string
+	Message string
 }
 
 type ImageResultContext struct {
@@ -89,6 +113,8 @@ type ImagesPageContext struct {
 	ImageResults     []ImageResultContext
 	Pagination       SinglePagePaginationContext
 	Navigation       SearchNavigationContext
+	Engine           EngineContext
+	Completions      []string
 	SearchCorrection SearchCorrectionContext
 }
 
@@ -97,5 +123,11 @@ type VideosPageContext struct {
 	VideoResults     []VideoResultContext
 	Pagination       SinglePagePaginationContext
 	Navigation       SearchNavigationContext
+	Engine           EngineContext
+	Completions      []string
 	SearchCorrection SearchCorrectionContext
 }
+
+type SuggestionsFragmentContext struct {
+	Completions []string
+}
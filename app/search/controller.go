@@ -1,46 +1,36 @@
 package search
 
 import (
-	"log"
+	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
 	"net/url"
-	"os"
 	"strconv"
 
-	"github.com/davecgh/go-spew/spew"
+	"sitelook/app/logging"
+
 	"github.com/gin-gonic/gin"
 )
 
-// func apiSearchRoute(c *gin.Context) {
-// 	searchTerm := c.Query("q")
-// 	startQuery := c.Query("start")
-
-// 	if len(searchTerm) == 0 {
-// 		c.Writer.WriteHeader(http.StatusBadRequest)
-// 		c.Writer.WriteString("empty search term")
-// 		return
-// 	}
-
-// 	start, _ := strconv.Atoi(startQuery)
-// 	searchPage, err := parseSearchPage(searchTerm, start)
-
-// 	if err != nil {
-// 		c.Writer.WriteHeader(http.StatusInternalServerError)
-// 		log.Fatal(err)
-// 	}
-
-// 	c.JSON(http.StatusOK, searchPage)
-// }
-
 func createHref(url *url.URL, query url.Values) string {
 	return url.Path + "?" + query.Encode()
 }
 
+// The interface language of requests without the `hl` parameter, the
+// backend's default when empty
+var defaultLanguage = ""
+
+func SetDefaultLanguage(language string) {
+	defaultLanguage = language
+}
+
 type SearchQueryParams struct {
 	Type              string
 	Start             int
 	SearchLanguage    string
 	InterfaceLanguage string
+	Backend           string
 }
 
 func createSearchQueryParams(context *gin.Context) SearchQueryParams {
@@ -48,6 +38,10 @@ func createSearchQueryParams(context *gin.Context) SearchQueryParams {
 	startQuery := context.Query("start")
 	lrQuery := context.Query("lr")
 	hlQuery := context.Query("hl")
+	if len(hlQuery) == 0 {
+		hlQuery = defaultLanguage
+	}
+	backendQuery := context.Query("backend")
 	start, _ := strconv.Atoi(startQuery)
 
 	return SearchQueryParams{
@@ -55,6 +49,7 @@ func createSearchQueryParams(context *gin.Context) SearchQueryParams {
 		Start:             start,
 		SearchLanguage:    lrQuery,
 		InterfaceLanguage: hlQuery,
+		Backend:           backendQuery,
 	}
 }
 
@@ -68,59 +63,150 @@ func SearchRoute(c *gin.Context) {
 		return
 	}
 
+	if _, err := getBackend(queryParams.Backend); err != nil {
+		c.HTML(http.StatusBadRequest, "error-page", ErrorPageContext{Title: "Unknown backend", Message: err.Error()})
+		return
+	}
+
+	waitForCompletions := startCompletions(c.Request.Context(), searchTerm, queryParams)
+
 	if queryParams.Type == "isch" {
-		searchResponse, err := ImageSearch(searchTerm, queryParams)
-		if err != nil {
-			log.Println(err)
+		searchResponse, err := ImageSearch(c.Request.Context(), searchTerm, queryParams)
+		setCacheHeader(c, searchResponse.Cached)
+		logSearch(c, searchResponse.Backend, countImageResults(searchResponse.ImagesPage), searchResponse.Cached, err)
+		logging.DumpPage(c, searchResponse)
+
+		if searchResponse.Type == SearchResponseCaptcha {
+			c.HTML(http.StatusOK, "captcha-page", createCaptchaPageContext(*searchResponse.Captcha))
+			return
+		}
+
+		if searchResponse.Type != SearchResponsePage {
+			renderSearchError(c, searchResponse.Backend, err)
+			return
 		}
-		imagesPageContext := createImagesPageContext(*searchResponse.ImagesPage, currentUrl)
+
+		engine := createEngineContext(searchResponse.Backend, resolveBackendName(queryParams.Backend, "isch"))
+		imagesPageContext := createImagesPageContext(*searchResponse.ImagesPage, engine, currentUrl)
+		imagesPageContext.Completions = waitForCompletions()
 		c.HTML(http.StatusOK, "image-search-page", imagesPageContext)
 		return
 	} else if queryParams.Type == "vid" {
-		searchResponse, err := VideoSearch(searchTerm, queryParams)
-		if err != nil {
-			log.Println(err)
+		searchResponse, err := VideoSearch(c.Request.Context(), searchTerm, queryParams)
+		setCacheHeader(c, searchResponse.Cached)
+		logSearch(c, searchResponse.Backend, countVideoResults(searchResponse.VideosPage), searchResponse.Cached, err)
+		logging.DumpPage(c, searchResponse)
+
+		if searchResponse.Type == SearchResponseCaptcha {
+			c.HTML(http.StatusOK, "captcha-page", createCaptchaPageContext(*searchResponse.Captcha))
+			return
 		}
-		videosPageContext := createVideosPageContext(*searchResponse.VideosPage, currentUrl)
-		c.HTML(http.StatusOK, "video-search-page", videosPageContext)
 
-		logFile, err := os.OpenFile("log.txt", os.O_CREATE|os.O_WRONLY, 0666)
-		if err != nil {
-			log.Fatal("error opening log file")
+		if searchResponse.Type != SearchResponsePage {
+			renderSearchError(c, searchResponse.Backend, err)
+			return
 		}
 
-		log.SetOutput(logFile)
-		spew.Fdump(log.Writer(), searchResponse)
-		defer logFile.Close()
-
+		engine := createEngineContext(searchResponse.Backend, resolveBackendName(queryParams.Backend, "vid"))
+		videosPageContext := createVideosPageContext(*searchResponse.VideosPage, engine, currentUrl)
+		videosPageContext.Completions = waitForCompletions()
+		c.HTML(http.StatusOK, "video-search-page", videosPageContext)
 		return
 	} else if len(queryParams.Type) > 0 {
 		// c.Redirect(http.StatusPermanentRedirect, fmt.Sprintf("https://google.com/search?q=%s&tbm=%s", searchTerm, searchType))
 		return
 	}
 
-	searchResponse, err := Search(searchTerm, queryParams)
-
-	if err != nil {
-		log.Fatal(err)
-	}
+	searchResponse, err := Search(c.Request.Context(), searchTerm, queryParams)
+	setCacheHeader(c, searchResponse.Cached)
+	logSearch(c, searchResponse.Backend, countSearchResults(searchResponse.SearchPage), searchResponse.Cached, err)
+	logging.DumpPage(c, searchResponse)
 
-	if searchResponse.Type == SearchResponseError {
-		log.Printf("search response error with code: %d", searchResponse.Status)
-	} else if searchResponse.Type == SearchResponsePage {
-		searchPageContext := createSearchPageContext(*searchResponse.SearchPage, currentUrl)
+	if searchResponse.Type == SearchResponsePage {
+		engine := createEngineContext(searchResponse.Backend, resolveBackendName(queryParams.Backend, ""))
+		searchPageContext := createSearchPageContext(*searchResponse.SearchPage, engine, currentUrl)
+		searchPageContext.Completions = waitForCompletions()
 		c.HTML(http.StatusOK, "search-page", searchPageContext)
 	} else if searchResponse.Type == SearchResponseCaptcha {
 		captchaPageContext := createCaptchaPageContext(*searchResponse.Captcha)
 		c.HTML(http.StatusOK, "captcha-page", captchaPageContext)
+	} else {
+		renderSearchError(c, searchResponse.Backend, err)
 	}
+}
+
+// logSearch adds the backend and the result count to the request log. Errors
+// are logged separately, their messages never contain the search term.
+func logSearch(c *gin.Context, backendName string, results int, cached bool, err error) {
+	logging.AddAttrs(c,
+		slog.String("backend", backendName),
+		slog.Int("results", results),
+		slog.Bool("cached", cached),
+	)
 
-	logFile, err := os.OpenFile("log.txt", os.O_CREATE|os.O_WRONLY, 0666)
 	if err != nil {
-		log.Fatal("error opening log file")
+		logging.Logger(c).Warn("search failed", "backend", backendName, "error", err)
+	}
+}
+
+func countSearchResults(page *SearchPage) int {
+	if page == nil {
+		return 0
+	}
+	return len(page.SearchResults)
+}
+
+func countImageResults(page *ImagesPage) int {
+	if page == nil {
+		return 0
+	}
+	return len(page.ImageResults)
+}
+
+func countVideoResults(page *VideosPage) int {
+	if page == nil {
+		return 0
+	}
+	return len(page.VideoResults)
+}
+
+func renderSearchError(c *gin.Context, backendName string, err error) {
+	status, errorPageContext := createErrorPageContext(backendName, err)
+	c.HTML(status, "error-page", errorPageContext)
+}
+
+// X-Sitelook-Cache tells whether the page was served from the result cache
+func setCacheHeader(c *gin.Context, cached bool) {
+	if cached {
+		c.Header("X-Sitelook-Cache", "HIT")
+	} else {
+		c.Header("X-Sitelook-Cache", "MISS")
+	}
+}
+
+// SuggestRoute responds in the OpenSearch suggestions format which browsers
+// request while the user types into the address bar. `format=html` responds
+// with a datalist fragment instead.
+func SuggestRoute(c *gin.Context) {
+	searchTerm := c.Query("q")
+	queryParams := createSearchQueryParams(c)
+	suggestions := []string{}
+
+	if len(searchTerm) > 0 {
+		// a skipped backend isn't asked on every keystroke until it recovers
+		backendSuggestions, err := Suggest(c.Request.Context(), searchTerm, queryParams)
+		if err == nil {
+			suggestions = backendSuggestions
+		} else if !errors.Is(err, ErrSuggestionsSkipped) {
+			logging.Logger(c).Warn("suggestions failed", "error", err)
+		}
+	}
+
+	if c.Query("format") == "html" {
+		c.HTML(http.StatusOK, "search-completions", SuggestionsFragmentContext{Completions: suggestions})
+		return
 	}
 
-	log.SetOutput(logFile)
-	spew.Fdump(log.Writer(), searchResponse)
-	defer logFile.Close()
+	body, _ := json.Marshal([]interface{}{searchTerm, suggestions})
+	c.Data(http.StatusOK, "application/x-suggestions+json; charset=utf-8", body)
 }
@@ -1,9 +1,14 @@
 package search
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+
+	"sitelook/app/imgproxy"
 )
 
 func createSearchCorrectionContext(searchCorrection SearchCorrection, currentUrl *url.URL) SearchCorrectionContext {
@@ -31,11 +36,18 @@ func makeUrlTitle(itemUrl string) (string, error) {
 func createSearchResultContext(searchResult SearchResult) SearchResultContext {
 	urlTitle, _ := makeUrlTitle(searchResult.Url)
 
+	engines := make([]string, len(searchResult.Engines))
+
+	for i := 0; i < len(searchResult.Engines); i++ {
+		engines[i] = backendTitle(searchResult.Engines[i])
+	}
+
 	return SearchResultContext{
 		Url:         searchResult.Url,
 		Title:       searchResult.Title,
 		UrlTitle:    urlTitle,
 		Description: searchResult.Description,
+		Engines:     engines,
 	}
 }
 
@@ -121,13 +133,30 @@ func createNavigationContext(currentUrl *url.URL) SearchNavigationContext {
 	return SearchNavigationContext{
 		CurrentSearchType: searchType,
 		SearchQueryParam:  tbm,
+		BackendQueryParam: query.Get("backend"),
 		AllSearchHref:     allSearchHref,
 		ImageSearchHref:   imageSearchHref,
 		VideoSearchHref:   videoSearchHref,
 	}
 }
 
-func createSearchPageContext(searchPage SearchPage, currentUrl *url.URL) SearchPageContext {
+func createSuggestionContexts(suggestions []string, currentUrl *url.URL) []SuggestionContext {
+	contexts := make([]SuggestionContext, len(suggestions))
+	query := currentUrl.Query()
+	query.Del("start")
+
+	for i := 0; i < len(suggestions); i++ {
+		query.Set("q", suggestions[i])
+		contexts[i] = SuggestionContext{
+			SearchTerm: suggestions[i],
+			Href:       createHref(currentUrl, query),
+		}
+	}
+
+	return contexts
+}
+
+func createSearchPageContext(searchPage SearchPage, engine EngineContext, currentUrl *url.URL) SearchPageContext {
 	searchResults := make([]SearchResultContext, len(searchPage.SearchResults))
 
 	for i := 0; i < len(searchPage.SearchResults); i++ {
@@ -139,7 +168,10 @@ func createSearchPageContext(searchPage SearchPage, currentUrl *url.URL) SearchP
 		SearchResults:    searchResults,
 		Pagination:       createSinglePagePaginationContext(searchPage.Pagination, currentUrl),
 		Navigation:       createNavigationContext(currentUrl),
+		Engine:           engine,
 		SearchCorrection: createSearchCorrectionContext(searchPage.SearchCorrection, currentUrl),
+		Answers:          searchPage.Answers,
+		Suggestions:      createSuggestionContexts(searchPage.Suggestions, currentUrl),
 	}
 }
 
@@ -147,7 +179,7 @@ func createImageResultContext(imageResult ImageResult) ImageResultContext {
 	return ImageResultContext{
 		Title:         imageResult.Title,
 		UrlTitle:      imageResult.UrlTitle,
-		ImageSrc:      imageResult.ImageSrc,
+		ImageSrc:      imgproxy.ProxyUrl(imageResult.ImageSrc),
 		TitleLinkHref: imageResult.TitleLinkHref,
 		ImageLinkHref: imageResult.ImageLinkHref,
 	}
@@ -159,13 +191,13 @@ func createVideoResultContext(videoResult VideoResult) VideoResultContext {
 	return VideoResultContext{
 		Title:         videoResult.Title,
 		UrlTitle:      urlTitle,
-		ImageSrc:      videoResult.ImageSrc,
+		ImageSrc:      imgproxy.ProxyUrl(videoResult.ImageSrc),
 		TitleLinkHref: videoResult.TitleLinkHref,
 		Description:   videoResult.Description,
 	}
 }
 
-func createImagesPageContext(imagesPage ImagesPage, currentUrl *url.URL) ImagesPageContext {
+func createImagesPageContext(imagesPage ImagesPage, engine EngineContext, currentUrl *url.URL) ImagesPageContext {
 	imageResults := make([]ImageResultContext, len(imagesPage.ImageResults))
 
 	for i := 0; i < len(imagesPage.ImageResults); i++ {
@@ -177,11 +209,12 @@ func createImagesPageContext(imagesPage ImagesPage, currentUrl *url.URL) ImagesP
 		ImageResults:     imageResults,
 		Pagination:       createSinglePagePaginationContext(imagesPage.Pagination, currentUrl),
 		Navigation:       createNavigationContext(currentUrl),
+		Engine:           engine,
 		SearchCorrection: SearchCorrectionContext{},
 	}
 }
 
-func createVideosPageContext(videosPage VideosPage, currentUrl *url.URL) VideosPageContext {
+func createVideosPageContext(videosPage VideosPage, engine EngineContext, currentUrl *url.URL) VideosPageContext {
 	videoResults := make([]VideoResultContext, len(videosPage.VideoResults))
 
 	for i := 0; i < len(videosPage.VideoResults); i++ {
@@ -193,25 +226,82 @@ func createVideosPageContext(videosPage VideosPage, currentUrl *url.URL) VideosP
 		VideoResults:     videoResults,
 		Pagination:       createSinglePagePaginationContext(videosPage.Pagination, currentUrl),
 		Navigation:       createNavigationContext(currentUrl),
+		Engine:           engine,
 		SearchCorrection: SearchCorrectionContext{},
 	}
 }
 
+func createEngineContext(answeredBackendName string, requestedBackendName string) EngineContext {
+	context := EngineContext{
+		Title: backendTitle(answeredBackendName),
+	}
+
+	if answeredBackendName != requestedBackendName {
+		context.FallbackFromTitle = backendTitle(requestedBackendName)
+	}
+
+	return context
+}
+
 func createEmptySearchPageContext() SearchPageContext {
 	return SearchPageContext{}
 }
 
 func createCaptchaPageContext(captchaPage CaptchaPage) CaptchaPageContext {
-	searchUrl := getSearchUrl(captchaPage.SearchTerm, 0, "", "", "")
-
 	return CaptchaPageContext{
-		SearchRedirectUrl: searchUrl,
+		SearchRedirectUrl: captchaPage.SearchUrl,
+		BackendTitle:      captchaPage.BackendTitle,
+	}
+}
+
+// createErrorPageContext describes the error without exposing upstream urls,
+// those are only logged
+func createErrorPageContext(backendName string, err error) (int, ErrorPageContext) {
+	searchError := &SearchError{}
+	if errors.As(err, &searchError) {
+		backendName = searchError.Backend
+	}
+
+	title := backendTitle(backendName)
+
+	if errors.Is(err, ErrSearchTypeNotSupported) {
+		return http.StatusNotImplemented, ErrorPageContext{
+			Title:   "Search type not supported",
+			Message: fmt.Sprintf("%s doesn't support this search type", title),
+		}
+	} else if errors.Is(err, ErrProxy) {
+		return http.StatusBadGateway, ErrorPageContext{
+			Title:   "Proxy unavailable",
+			Message: fmt.Sprintf("%s couldn't be reached because the instance's outbound proxies failed", title),
+		}
+	} else if errors.Is(err, ErrNetwork) {
+		return http.StatusBadGateway, ErrorPageContext{
+			Title:   "Backend unavailable",
+			Message: fmt.Sprintf("%s couldn't be reached", title),
+		}
+	} else if errors.Is(err, ErrUpstreamStatus) {
+		return http.StatusBadGateway, ErrorPageContext{
+			Title:   "Backend error",
+			Message: fmt.Sprintf("%s responded with status %d", title, searchError.Status),
+		}
+	} else if errors.Is(err, ErrParse) {
+		return http.StatusBadGateway, ErrorPageContext{
+			Title:   "Results couldn't be read",
+			Message: fmt.Sprintf("The page returned by %s couldn't be parsed, its layout may have changed", title),
+		}
+	}
+
+	return http.StatusInternalServerError, ErrorPageContext{
+		Title:   "Search failed",
+		Message: fmt.Sprintf("Searching with %s failed", title),
 	}
 }
 
-func createCaptchaPage(searchTerm string) CaptchaPage {
+func createCaptchaPage(searchTerm string, backend Backend, searchUrl string) CaptchaPage {
 	return CaptchaPage{
-		SearchTerm: searchTerm,
+		SearchTerm:   searchTerm,
+		BackendTitle: backend.Title(),
+		SearchUrl:    searchUrl,
 	}
 }
 
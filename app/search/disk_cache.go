@@ -0,0 +1,333 @@
+package search
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const responseMetaExtension = ".json"
+const responseBodyExtension = ".body"
+
+// responseMeta is stored next to each response body kept on disk. The files
+// are kept readable so they can be used as a corpus for testing parsers.
+type responseMeta struct {
+	Url       string    `json:"url"`
+	Status    int       `json:"status"`
+	Size      int64     `json:"size"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+type diskCacheEntry struct {
+	name string
+	meta responseMeta
+}
+
+// diskCache keeps raw upstream responses on disk so they survive restarts.
+// The index lives in memory and is rebuilt from the metadata files on start,
+// entries are evicted in least recently used order once the size limit is hit.
+type diskCache struct {
+	mutex   sync.Mutex
+	dir     string
+	maxSize int64
+	ttl     time.Duration
+	size    int64
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+// Disabled unless a directory is configured
+var responseCache *diskCache = nil
+
+// SetDiskCache stores upstream responses in dir, keeping at most maxSize bytes
+// of response bodies for ttl. An empty dir disables the cache.
+func SetDiskCache(dir string, maxSize int64, ttl time.Duration) error {
+	if len(dir) == 0 {
+		responseCache = nil
+		return nil
+	}
+
+	if maxSize <= 0 {
+		return errors.New("disk cache size must be positive")
+	}
+
+	cache, err := openDiskCache(dir, maxSize, ttl)
+	if err != nil {
+		return err
+	}
+
+	responseCache = cache
+	return nil
+}
+
+func openDiskCache(dir string, maxSize int64, ttl time.Duration) (*diskCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	cache := &diskCache{
+		dir:     dir,
+		maxSize: maxSize,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := []diskCacheEntry{}
+	bodies := map[string]bool{}
+
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		// temporary files of writes interrupted by a crash
+		if strings.HasPrefix(file.Name(), ".tmp-") {
+			os.Remove(filepath.Join(dir, file.Name()))
+			continue
+		}
+
+		if name, isBody := strings.CutSuffix(file.Name(), responseBodyExtension); isBody {
+			bodies[name] = true
+			continue
+		}
+
+		name, isMeta := strings.CutSuffix(file.Name(), responseMetaExtension)
+		if !isMeta {
+			continue
+		}
+
+		meta, err := readResponseMeta(cache.path(name, responseMetaExtension))
+		if err != nil {
+			slog.Warn("disk cache entry removed", "file", file.Name(), "error", err)
+			os.Remove(cache.path(name, responseMetaExtension))
+			continue
+		}
+
+		loaded = append(loaded, diskCacheEntry{name: name, meta: meta})
+	}
+
+	// entries are only complete with both files, the other file of a broken
+	// entry would never be served or evicted
+	complete := loaded[:0]
+	for _, entry := range loaded {
+		if bodies[entry.name] {
+			complete = append(complete, entry)
+			delete(bodies, entry.name)
+		} else {
+			os.Remove(cache.path(entry.name, responseMetaExtension))
+		}
+	}
+	loaded = complete
+
+	for name := range bodies {
+		os.Remove(cache.path(name, responseBodyExtension))
+	}
+
+	// the most recently fetched responses are the last ones to be evicted
+	sort.Slice(loaded, func(i, j int) bool {
+		return loaded[i].meta.FetchedAt.After(loaded[j].meta.FetchedAt)
+	})
+
+	for i := range loaded {
+		cache.entries[loaded[i].name] = cache.order.PushBack(&loaded[i])
+		cache.size += loaded[i].meta.Size
+	}
+
+	cache.mutex.Lock()
+	cache.evict()
+	cache.mutex.Unlock()
+
+	return cache, nil
+}
+
+func diskCacheName(url string) string {
+	hash := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(hash[:])
+}
+
+func (c *diskCache) path(name string, extension string) string {
+	return filepath.Join(c.dir, name+extension)
+}
+
+func readResponseMeta(path string) (responseMeta, error) {
+	meta := responseMeta{}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return meta, err
+	}
+
+	err = json.Unmarshal(data, &meta)
+	return meta, err
+}
+
+// writeFile writes through a temporary file so a crash never leaves a
+// partially written response behind
+func writeFile(path string, data []byte) error {
+	tempPath, err := writeTempFile(filepath.Dir(path), data)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tempPath, path)
+}
+
+// writeTempFile writes data to a new temporary file in dir, which is renamed
+// into place once it is complete
+func writeTempFile(dir string, data []byte) (string, error) {
+	file, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+
+	return file.Name(), nil
+}
+
+// writeResponse stores the body and its metadata under the path without an
+// extension. The metadata file is written last, a body without it is ignored.
+func writeResponse(path string, meta responseMeta, body []byte) error {
+	data, err := json.MarshalIndent(meta, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	if err := writeFile(path+responseBodyExtension, body); err != nil {
+		return err
+	}
+
+	return writeFile(path+responseMetaExtension, data)
+}
+
+func (c *diskCache) get(url string) (body []byte, status int, ok bool) {
+	name := diskCacheName(url)
+
+	c.mutex.Lock()
+	element, ok := c.entries[name]
+	if !ok {
+		c.mutex.Unlock()
+		return nil, 0, false
+	}
+
+	entry := element.Value.(*diskCacheEntry)
+	if time.Since(entry.meta.FetchedAt) > c.ttl {
+		c.remove(element)
+		c.mutex.Unlock()
+		return nil, 0, false
+	}
+
+	c.order.MoveToFront(element)
+	status = entry.meta.Status
+	c.mutex.Unlock()
+
+	body, err := os.ReadFile(c.path(name, responseBodyExtension))
+	if err != nil {
+		// evicted while being read
+		return nil, 0, false
+	}
+
+	return body, status, true
+}
+
+func (c *diskCache) put(url string, body []byte, status int) {
+	name := diskCacheName(url)
+	meta := responseMeta{
+		Url:       url,
+		Status:    status,
+		Size:      int64(len(body)),
+		FetchedAt: time.Now(),
+	}
+
+	data, err := json.MarshalIndent(meta, "", "    ")
+	if err != nil {
+		slog.Error("disk cache write failed", "error", err)
+		return
+	}
+
+	// the files are written before locking, only renaming them into place
+	// and updating the index block other searches
+	bodyPath, err := writeTempFile(c.dir, body)
+	if err != nil {
+		slog.Error("disk cache write failed", "error", err)
+		return
+	}
+
+	metaPath, err := writeTempFile(c.dir, data)
+	if err != nil {
+		os.Remove(bodyPath)
+		slog.Error("disk cache write failed", "error", err)
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// the metadata file is renamed last, a body without it is removed when the
+	// cache is opened again
+	if err := os.Rename(bodyPath, c.path(name, responseBodyExtension)); err != nil {
+		os.Remove(bodyPath)
+		os.Remove(metaPath)
+		slog.Error("disk cache write failed", "error", err)
+		return
+	}
+
+	if err := os.Rename(metaPath, c.path(name, responseMetaExtension)); err != nil {
+		os.Remove(metaPath)
+		slog.Error("disk cache write failed", "error", err)
+		return
+	}
+
+	if element, ok := c.entries[name]; ok {
+		entry := element.Value.(*diskCacheEntry)
+		c.size -= entry.meta.Size
+		entry.meta = meta
+		c.order.MoveToFront(element)
+	} else {
+		c.entries[name] = c.order.PushFront(&diskCacheEntry{name: name, meta: meta})
+	}
+
+	c.size += meta.Size
+	c.evict()
+}
+
+// remove and evict expect the mutex to be locked
+func (c *diskCache) remove(element *list.Element) {
+	entry := element.Value.(*diskCacheEntry)
+
+	c.order.Remove(element)
+	delete(c.entries, entry.name)
+	c.size -= entry.meta.Size
+
+	os.Remove(c.path(entry.name, responseMetaExtension))
+	os.Remove(c.path(entry.name, responseBodyExtension))
+}
+
+func (c *diskCache) evict() {
+	for c.size > c.maxSize && c.order.Len() > 0 {
+		c.remove(c.order.Back())
+	}
+}
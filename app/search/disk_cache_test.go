@@ -0,0 +1,189 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func openTestDiskCache(t *testing.T, dir string, maxSize int64, ttl time.Duration) *diskCache {
+	t.Helper()
+
+	cache, err := openDiskCache(dir, maxSize, ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cache
+}
+
+// cachedUrls returns the urls of the cached responses, in the order they're
+// evicted last to first
+func cachedUrls(cache *diskCache) []string {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	urls := []string{}
+	for element := cache.order.Front(); element != nil; element = element.Next() {
+		urls = append(urls, element.Value.(*diskCacheEntry).meta.Url)
+	}
+
+	return urls
+}
+
+// cacheFiles lists the files in the cache directory
+func cacheFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func TestDiskCacheStoresResponses(t *testing.T) {
+	cache := openTestDiskCache(t, t.TempDir(), 1024, time.Hour)
+
+	if _, _, ok := cache.get("https://backend.example/search?q=golang"); ok {
+		t.Fatal("empty cache has a response")
+	}
+
+	cache.put("https://backend.example/search?q=golang", []byte("results"), 200)
+
+	body, status, ok := cache.get("https://backend.example/search?q=golang")
+	if !ok || string(body) != "results" || status != 200 {
+		t.Errorf("got %q with status %d, found %t, want the stored response", body, status, ok)
+	}
+}
+
+func TestDiskCacheEviction(t *testing.T) {
+	dir := t.TempDir()
+	cache := openTestDiskCache(t, dir, 10, time.Hour)
+
+	cache.put("a", []byte("aaaa"), 200)
+	cache.put("b", []byte("bbbb"), 200)
+
+	// a is used again, so b is the least recently used response
+	cache.get("a")
+	cache.put("c", []byte("cccc"), 200)
+
+	if got, want := cachedUrls(cache), []string{"c", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got cached urls %v, want %v", got, want)
+	}
+
+	if cache.size != 8 {
+		t.Errorf("got size %d, want 8", cache.size)
+	}
+
+	if _, err := os.Stat(cache.path(diskCacheName("b"), responseBodyExtension)); !os.IsNotExist(err) {
+		t.Errorf("body of the evicted response wasn't removed: %v", err)
+	}
+
+	if got := len(cacheFiles(t, dir)); got != 4 {
+		t.Errorf("got %d files, want the body and metadata of 2 responses", got)
+	}
+}
+
+func TestDiskCacheReplacesResponses(t *testing.T) {
+	cache := openTestDiskCache(t, t.TempDir(), 1024, time.Hour)
+
+	cache.put("a", []byte("old results"), 200)
+	cache.put("a", []byte("new"), 200)
+
+	body, _, _ := cache.get("a")
+	if string(body) != "new" || cache.size != 3 {
+		t.Errorf("got %q and size %d, want the new response only", body, cache.size)
+	}
+}
+
+func TestDiskCacheTtl(t *testing.T) {
+	dir := t.TempDir()
+	cache := openTestDiskCache(t, dir, 1024, 20*time.Millisecond)
+
+	cache.put("a", []byte("aaaa"), 200)
+	time.Sleep(30 * time.Millisecond)
+
+	if _, _, ok := cache.get("a"); ok {
+		t.Error("expired response was served")
+	}
+
+	if files := cacheFiles(t, dir); len(files) > 0 || cache.size != 0 {
+		t.Errorf("got files %v and size %d, want the expired response removed", files, cache.size)
+	}
+}
+
+func TestDiskCacheReopen(t *testing.T) {
+	dir := t.TempDir()
+	cache := openTestDiskCache(t, dir, 1024, time.Hour)
+
+	cache.put("a", []byte("aaaa"), 200)
+	time.Sleep(time.Millisecond)
+	cache.put("b", []byte("bbbb"), 404)
+	time.Sleep(time.Millisecond)
+	cache.put("c", []byte("cccc"), 200)
+
+	reopened := openTestDiskCache(t, dir, 1024, time.Hour)
+
+	if got, want := cachedUrls(reopened), []string{"c", "b", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got cached urls %v, want %v ordered by fetch time", got, want)
+	}
+
+	body, status, ok := reopened.get("b")
+	if !ok || string(body) != "bbbb" || status != 404 || reopened.size != 12 {
+		t.Errorf("got %q with status %d and size %d, want the stored response", body, status, reopened.size)
+	}
+
+	// a smaller cache keeps the most recently fetched responses
+	smaller := openTestDiskCache(t, dir, 8, time.Hour)
+
+	if got, want := cachedUrls(smaller), []string{"c", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got cached urls %v, want %v", got, want)
+	}
+}
+
+func TestDiskCacheRemovesIncompleteEntries(t *testing.T) {
+	dir := t.TempDir()
+	cache := openTestDiskCache(t, dir, 1024, time.Hour)
+	cache.put("a", []byte("aaaa"), 200)
+
+	leftovers := map[string]string{
+		// body of a write whose metadata file wasn't renamed
+		diskCacheName("b") + responseBodyExtension: "bbbb",
+		// metadata whose body is gone
+		diskCacheName("c") + responseMetaExtension: `{"url": "c", "status": 200, "size": 4}`,
+		// unreadable metadata
+		diskCacheName("d") + responseMetaExtension: `{"url": `,
+		diskCacheName("d") + responseBodyExtension: "dddd",
+		// temporary files of interrupted writes
+		".tmp-123456": "eeee",
+		".tmp-654321": "ffff",
+	}
+
+	for name, data := range leftovers {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reopened := openTestDiskCache(t, dir, 1024, time.Hour)
+
+	want := []string{diskCacheName("a") + responseBodyExtension, diskCacheName("a") + responseMetaExtension}
+	if got := cacheFiles(t, dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("got files %v, want %v", got, want)
+	}
+
+	if got := cachedUrls(reopened); !reflect.DeepEqual(got, []string{"a"}) || reopened.size != 4 {
+		t.Errorf("got cached urls %v and size %d, want the complete entry only", got, reopened.size)
+	}
+}
@@ -0,0 +1,85 @@
+package search
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+const DuckDuckGoBackendName = "duckduckgo"
+
+// The html version returns about 30 results per page
+const duckDuckGoPageSize = 30
+
+// DuckDuckGoBackend queries the no-js version of DuckDuckGo. It only supports
+// the "All" search type.
+type DuckDuckGoBackend struct{}
+
+func (b *DuckDuckGoBackend) Name() string {
+	return DuckDuckGoBackendName
+}
+
+func (b *DuckDuckGoBackend) Title() string {
+	return "DuckDuckGo"
+}
+
+func (b *DuckDuckGoBackend) PageSize() int {
+	return duckDuckGoPageSize
+}
+
+func (b *DuckDuckGoBackend) SearchUrl(searchTerm string, searchType string, params SearchQueryParams) (string, error) {
+	if len(searchType) > 0 {
+		return "", ErrSearchTypeNotSupported
+	}
+
+	return getDuckDuckGoSearchUrl(searchTerm, params.Start), nil
+}
+
+func (b *DuckDuckGoBackend) Fetch(ctx context.Context, searchUrl string) (body []byte, err error, status int) {
+	return getDocument(ctx, b.Name(), searchUrl, nil)
+}
+
+func (b *DuckDuckGoBackend) ParseSearchPage(body []byte, params SearchQueryParams) (*SearchPage, error) {
+	document, err := newDocument(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseDuckDuckGoSearchPage(document, params.Start)
+}
+
+func (b *DuckDuckGoBackend) ParseImagesPage(body []byte, params SearchQueryParams) (ImagesPage, error) {
+	return ImagesPage{}, ErrSearchTypeNotSupported
+}
+
+func (b *DuckDuckGoBackend) ParseVideosPage(body []byte, params SearchQueryParams) (VideosPage, error) {
+	return VideosPage{}, ErrSearchTypeNotSupported
+}
+
+func getDuckDuckGoSearchUrl(searchTerm string, start int) string {
+	searchUrl, _ := url.Parse("https://html.duckduckgo.com/html/")
+	query := searchUrl.Query()
+
+	query.Add("q", searchTerm)
+
+	// same fields the "Next" and "Previous" forms submit
+	if start > 0 {
+		query.Add("s", strconv.Itoa(start))
+		query.Add("dc", strconv.Itoa(start+1))
+	}
+
+	searchUrl.RawQuery = query.Encode()
+	return searchUrl.String()
+}
+
+func (b *DuckDuckGoBackend) SuggestionsUrl(searchTerm string, params SearchQueryParams) string {
+	suggestionsUrl, _ := url.Parse("https://duckduckgo.com/ac/")
+	query := suggestionsUrl.Query()
+
+	query.Add("q", searchTerm)
+	// OpenSearch suggestions format
+	query.Add("type", "list")
+
+	suggestionsUrl.RawQuery = query.Encode()
+	return suggestionsUrl.String()
+}
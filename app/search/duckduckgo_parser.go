@@ -0,0 +1,142 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func parseDuckDuckGoSearchResults(document *goquery.Document) []SearchResult {
+	results := []SearchResult{}
+
+	document.Find("#links .result").Each(func(i int, searchItem *goquery.Selection) {
+		// sponsored results
+		if searchItem.HasClass("result--ad") {
+			return
+		}
+
+		titleLink := findSingle(searchItem, "a.result__a")
+		if selectionEmpty(titleLink) {
+			return
+		}
+
+		title := strings.TrimSpace(titleLink.Text())
+		resultUrl := duckDuckGoRedirectTarget(titleLink.AttrOr("href", ""))
+		description := strings.TrimSpace(findSingle(searchItem, ".result__snippet").Text())
+
+		results = append(results, SearchResult{
+			Url:         resultUrl,
+			Title:       title,
+			Description: description,
+		})
+	})
+
+	return results
+}
+
+// duckDuckGoRedirectTarget extracts the result url from
+// `//duckduckgo.com/l/?uddg=[result url]&rut=...` links
+func duckDuckGoRedirectTarget(href string) string {
+	hrefUrl, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+
+	if target := hrefUrl.Query().Get("uddg"); len(target) > 0 {
+		return target
+	}
+
+	if strings.HasPrefix(href, "//") {
+		return "https:" + href
+	}
+
+	return href
+}
+
+// Pagination is made of forms with hidden inputs. Each form has a submit
+// button labeled "Previous" or "Next" and the `s` input holding the offset.
+func parseDuckDuckGoPagination(document *goquery.Document, start int) (SinglePagePagination, error) {
+	pagination := SinglePagePagination{
+		PreviousLinkPresent: false,
+		PreviousOffset:      0,
+		NextLinkPresent:     false,
+		NextOffset:          0,
+		CurrentTitle:        "",
+	}
+
+	forms := document.Find(".nav-link form")
+	if selectionEmpty(forms) {
+		return pagination, errors.New("pagination not found")
+	}
+
+	forms.Each(func(i int, form *goquery.Selection) {
+		label := findSingle(form, "input[type=\"submit\"]").AttrOr("value", "")
+		offset, err := strconv.Atoi(findSingle(form, "input[name=\"s\"]").AttrOr("value", ""))
+		if err != nil {
+			return
+		}
+
+		if strings.EqualFold(label, "Previous") {
+			pagination.PreviousLinkPresent = true
+			pagination.PreviousOffset = offset
+		} else if strings.EqualFold(label, "Next") {
+			pagination.NextLinkPresent = true
+			pagination.NextOffset = offset
+		}
+	})
+
+	// DuckDuckGo doesn't number its pages
+	if start > 0 {
+		pagination.CurrentTitle = fmt.Sprintf("%d+", start+1)
+	}
+
+	return pagination, nil
+}
+
+func parseDuckDuckGoSearchCorrection(document *goquery.Document) SearchCorrection {
+	correctionContainer := findSingle(document.Selection, "#did_you_mean")
+
+	correction := SearchCorrection{
+		Present: false,
+	}
+
+	correctionLink := findSingle(correctionContainer, "a")
+	if selectionEmpty(correctionLink) {
+		return correction
+	}
+
+	correctionUrl, err := url.Parse(correctionLink.AttrOr("href", ""))
+	if err != nil {
+		return correction
+	}
+
+	correction.Present = true
+	correction.Title = strings.TrimSpace(strings.TrimSuffix(correctionContainer.Contents().First().Text(), ":"))
+	correction.CorrectSearchTerm = correctionUrl.Query().Get("q")
+
+	return correction
+}
+
+func parseDuckDuckGoSearchPage(document *goquery.Document, start int) (*SearchPage, error) {
+	searchInput := findSingle(document.Selection, "input[name=\"q\"]")
+	if selectionEmpty(searchInput) {
+		return nil, errors.New("search input not found")
+	}
+
+	searchResults := parseDuckDuckGoSearchResults(document)
+	searchCorrection := parseDuckDuckGoSearchCorrection(document)
+	pagination, _ := parseDuckDuckGoPagination(document, start)
+
+	searchPage := SearchPage{
+		SearchTerm:       searchInput.AttrOr("value", ""),
+		SearchResults:    searchResults,
+		Pagination:       pagination,
+		SearchCorrection: searchCorrection,
+	}
+
+	return &searchPage, nil
+}
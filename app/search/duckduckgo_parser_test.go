@@ -0,0 +1,127 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func loadFixture(t *testing.T, name string) *goquery.Document {
+	t.Helper()
+
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	document, err := newDocument(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return document
+}
+
+func TestParseDuckDuckGoSearchPage(t *testing.T) {
+	tests := []struct {
+		fixture string
+		start   int
+		want    SearchPage
+	}{
+		{
+			fixture: "duckduckgo/search-first-page.html",
+			start:   0,
+			want: SearchPage{
+				SearchTerm: "golang",
+				SearchResults: []SearchResult{
+					{
+						Url:         "https://go.dev/",
+						Title:       "The Go Programming Language",
+						Description: "Go is an open source programming language that makes it simple to build secure, scalable systems.",
+					},
+					{
+						Url:         "https://en.wikipedia.org/wiki/Go_(programming_language)",
+						Title:       "Go (programming language) - Wikipedia",
+						Description: "Go is a statically typed, compiled high-level programming language designed at Google.",
+					},
+					{
+						Url:         "https://github.com/golang/go",
+						Title:       "golang/go: The Go programming language - GitHub",
+						Description: "",
+					},
+				},
+				Pagination: SinglePagePagination{
+					NextLinkPresent: true,
+					NextOffset:      30,
+				},
+			},
+		},
+		{
+			fixture: "duckduckgo/search-middle-page.html",
+			start:   30,
+			want: SearchPage{
+				SearchTerm: "golnag",
+				SearchResults: []SearchResult{
+					{
+						Url:         "https://gobyexample.com/",
+						Title:       "Go by Example",
+						Description: "Go by Example is a hands-on introduction to Go using annotated example programs.",
+					},
+				},
+				Pagination: SinglePagePagination{
+					PreviousLinkPresent: true,
+					PreviousOffset:      0,
+					NextLinkPresent:     true,
+					NextOffset:          80,
+					CurrentTitle:        "31+",
+				},
+				SearchCorrection: SearchCorrection{
+					Present:           true,
+					Title:             "Including results for",
+					CorrectSearchTerm: "golang",
+				},
+			},
+		},
+		{
+			fixture: "duckduckgo/search-no-results.html",
+			start:   0,
+			want: SearchPage{
+				SearchTerm:    "qwxzvbnmlkj",
+				SearchResults: []SearchResult{},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.fixture, func(t *testing.T) {
+			searchPage, err := parseDuckDuckGoSearchPage(loadFixture(t, test.fixture), test.start)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(*searchPage, test.want) {
+				t.Errorf("got %+v, want %+v", *searchPage, test.want)
+			}
+		})
+	}
+}
+
+func TestGetDuckDuckGoSearchUrl(t *testing.T) {
+	tests := []struct {
+		start int
+		want  string
+	}{
+		{start: 0, want: "https://html.duckduckgo.com/html/?q=go+lang"},
+		{start: 30, want: "https://html.duckduckgo.com/html/?dc=31&q=go+lang&s=30"},
+	}
+
+	for _, test := range tests {
+		got := getDuckDuckGoSearchUrl("go lang", test.start)
+		if got != test.want {
+			t.Errorf("start %d: got %q, want %q", test.start, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of search errors, checked with errors.Is
+var (
+	ErrCaptcha        = errors.New("backend requires captcha")
+	ErrUpstreamStatus = errors.New("backend responded with an error status")
+	ErrParse          = errors.New("backend page couldn't be parsed")
+	ErrNetwork        = errors.New("backend couldn't be reached")
+	ErrProxy          = errors.New("backend couldn't be reached through the proxy")
+)
+
+// SearchError tells which backend request failed and why
+type SearchError struct {
+	Kind    error
+	Backend string
+	Url     string
+	Status  int
+	Err     error
+}
+
+func (e *SearchError) Error() string {
+	message := fmt.Sprintf("%s: %s", e.Backend, e.Kind)
+
+	if e.Status != 0 {
+		message += fmt.Sprintf(" (status %d)", e.Status)
+	}
+
+	if e.Err != nil {
+		message += ": " + e.Err.Error()
+	}
+
+	return message
+}
+
+func (e *SearchError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// fetchError returns the response type and the error of a request which
+// didn't get a response, proxy failures are told apart from the backend's
+func fetchError(backend Backend, searchUrl string, err error) (int, error) {
+	if errors.Is(err, ErrProxy) {
+		return SearchResponseProxyError, newSearchError(ErrProxy, backend, searchUrl, 0, err)
+	}
+	return SearchResponseError, newSearchError(ErrNetwork, backend, searchUrl, 0, err)
+}
+
+func newSearchError(kind error, backend Backend, searchUrl string, status int, err error) error {
+	return &SearchError{
+		Kind:    kind,
+		Backend: backend.Name(),
+		Url:     searchUrl,
+		Status:  status,
+		Err:     err,
+	}
+}
@@ -0,0 +1,165 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Backends tried in order when the requested backend fails
+var fallbackBackendNames = []string{}
+
+var breakerThreshold = 3
+var breakerCooldown = 5 * time.Minute
+var breakers = map[string]*circuitBreaker{}
+var breakersMutex sync.Mutex
+
+// circuitBreaker stops sending requests to a backend after repeated failures.
+// The backend is skipped until the cool-down period passes.
+type circuitBreaker struct {
+	mutex     sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+func (b *circuitBreaker) allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return time.Now().After(b.openUntil)
+}
+
+func (b *circuitBreaker) recordSuccess() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.failures = 0
+}
+
+// A blocked backend (e.g. one requiring captcha) is opened right away, other
+// failures open it once they reach the threshold
+func (b *circuitBreaker) recordFailure(blocked bool) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.failures++
+
+	if blocked || b.failures >= breakerThreshold {
+		b.failures = 0
+		b.openUntil = time.Now().Add(breakerCooldown)
+		return true
+	}
+
+	return false
+}
+
+func getBreaker(backendName string) *circuitBreaker {
+	breakersMutex.Lock()
+	defer breakersMutex.Unlock()
+
+	breaker, ok := breakers[backendName]
+	if !ok {
+		breaker = &circuitBreaker{}
+		breakers[backendName] = breaker
+	}
+
+	return breaker
+}
+
+// SetFallbackBackends selects backends which are tried in order when the
+// requested backend fails or requires captcha.
+func SetFallbackBackends(names []string) error {
+	for _, name := range names {
+		if _, ok := backends[name]; !ok {
+			return fmt.Errorf("unknown fallback backend %q (available: %v)", name, BackendNames())
+		}
+	}
+
+	fallbackBackendNames = names
+	return nil
+}
+
+// SetCircuitBreaker sets the number of consecutive failures after which
+// a backend is skipped and for how long.
+func SetCircuitBreaker(threshold int, cooldown time.Duration) {
+	breakerThreshold = threshold
+	breakerCooldown = cooldown
+}
+
+// getFailoverChain returns the requested backend followed by the fallback
+// backends, leaving out the ones with an open circuit breaker. The requested
+// backend is still tried when every backend is skipped.
+func getFailoverChain(backendName string) ([]Backend, error) {
+	primary, err := getBackend(backendName)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := []Backend{}
+
+	if getBreaker(primary.Name()).allow() {
+		chain = append(chain, primary)
+	}
+
+	for _, name := range fallbackBackendNames {
+		if name == primary.Name() || !getBreaker(name).allow() {
+			continue
+		}
+
+		chain = append(chain, backends[name])
+	}
+
+	if len(chain) == 0 {
+		chain = append(chain, primary)
+	}
+
+	return chain, nil
+}
+
+// isFailover records the outcome of a backend request in its circuit breaker
+// and reports whether the next backend should be tried. Requests cancelled by
+// the caller aren't held against the backend. Neither are proxy failures, the
+// fallback backends would be requested through the same proxies.
+func isFailover(ctx context.Context, backend Backend, responseType int, status int, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, ErrProxy) {
+		return false
+	}
+
+	if errors.Is(err, ErrSearchTypeNotSupported) {
+		return true
+	}
+
+	breaker := getBreaker(backend.Name())
+
+	if err == nil && responseType == SearchResponsePage {
+		breaker.recordSuccess()
+		return false
+	}
+
+	// other failures (e.g. a page which couldn't be parsed) are still tried
+	// on the next backend, but don't take the backend out for everyone
+	if !isBackendFailure(responseType, status, err) {
+		return true
+	}
+
+	if breaker.recordFailure(responseType == SearchResponseCaptcha || errors.Is(err, ErrCaptcha)) {
+		slog.Warn("backend skipped", "backend", backend.Name(), "cooldown", breakerCooldown, "status", status, "error", err)
+	}
+
+	return true
+}
+
+// isBackendFailure tells whether a failed request is held against the
+// backend: captchas, 429 and 5xx statuses and backends which couldn't be
+// reached
+func isBackendFailure(responseType int, status int, err error) bool {
+	if responseType == SearchResponseCaptcha || errors.Is(err, ErrCaptcha) || errors.Is(err, ErrNetwork) {
+		return true
+	}
+
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
@@ -0,0 +1,269 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// resetBreakers gives a test fresh circuit breakers and restores the
+// previous ones and settings afterwards
+func resetBreakers(t *testing.T, threshold int, cooldown time.Duration) {
+	t.Helper()
+
+	previousBreakers := breakers
+	previousThreshold, previousCooldown := breakerThreshold, breakerCooldown
+	previousFallbacks := fallbackBackendNames
+
+	breakers = map[string]*circuitBreaker{}
+	SetCircuitBreaker(threshold, cooldown)
+
+	t.Cleanup(func() {
+		breakers = previousBreakers
+		SetCircuitBreaker(previousThreshold, previousCooldown)
+		fallbackBackendNames = previousFallbacks
+	})
+}
+
+func TestCircuitBreakerThreshold(t *testing.T) {
+	resetBreakers(t, 3, time.Minute)
+	breaker := &circuitBreaker{}
+
+	for i := 1; i < 3; i++ {
+		if breaker.recordFailure(false) {
+			t.Fatalf("breaker opened after %d failures, threshold is 3", i)
+		}
+		if !breaker.allow() {
+			t.Fatalf("breaker doesn't allow requests after %d failures", i)
+		}
+	}
+
+	if !breaker.recordFailure(false) {
+		t.Fatal("breaker didn't open after 3 failures")
+	}
+	if breaker.allow() {
+		t.Fatal("open breaker allows requests")
+	}
+}
+
+func TestCircuitBreakerSuccessResetsFailures(t *testing.T) {
+	resetBreakers(t, 2, time.Minute)
+	breaker := &circuitBreaker{}
+
+	breaker.recordFailure(false)
+	breaker.recordSuccess()
+
+	if breaker.recordFailure(false) {
+		t.Fatal("breaker opened although a success came between the failures")
+	}
+}
+
+func TestCircuitBreakerCooldown(t *testing.T) {
+	resetBreakers(t, 1, 20*time.Millisecond)
+	breaker := &circuitBreaker{}
+
+	breaker.recordFailure(false)
+	if breaker.allow() {
+		t.Fatal("open breaker allows requests")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	if !breaker.allow() {
+		t.Fatal("breaker doesn't allow requests after the cooldown")
+	}
+}
+
+func TestCircuitBreakerCaptchaOpensImmediately(t *testing.T) {
+	resetBreakers(t, 3, time.Minute)
+	breaker := &circuitBreaker{}
+
+	if !breaker.recordFailure(true) {
+		t.Fatal("breaker didn't open on a captcha")
+	}
+	if breaker.allow() {
+		t.Fatal("breaker allows requests after a captcha")
+	}
+}
+
+func chainNames(chain []Backend) []string {
+	names := []string{}
+	for _, backend := range chain {
+		names = append(names, backend.Name())
+	}
+	return names
+}
+
+func TestGetFailoverChain(t *testing.T) {
+	resetBreakers(t, 1, time.Minute)
+
+	if err := SetFallbackBackends([]string{DuckDuckGoBackendName, GoogleBackendName, BingBackendName}); err != nil {
+		t.Fatal(err)
+	}
+
+	chain, err := getFailoverChain(GoogleBackendName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := chainNames(chain), []string{"google", "duckduckgo", "bing"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got chain %v, want %v", got, want)
+	}
+
+	getBreaker(DuckDuckGoBackendName).recordFailure(false)
+
+	chain, _ = getFailoverChain(GoogleBackendName)
+	if got, want := chainNames(chain), []string{"google", "bing"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got chain %v with duckduckgo skipped, want %v", got, want)
+	}
+
+	getBreaker(GoogleBackendName).recordFailure(false)
+
+	chain, _ = getFailoverChain(GoogleBackendName)
+	if got, want := chainNames(chain), []string{"bing"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got chain %v with google skipped, want %v", got, want)
+	}
+
+	getBreaker(BingBackendName).recordFailure(false)
+
+	// every backend is skipped, the requested one is still tried
+	chain, _ = getFailoverChain(GoogleBackendName)
+	if got, want := chainNames(chain), []string{"google"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got chain %v with every backend skipped, want %v", got, want)
+	}
+
+	if _, err := getFailoverChain("unknown"); err == nil {
+		t.Error("unknown backend didn't fail")
+	}
+}
+
+func TestIsFailover(t *testing.T) {
+	backend := &GoogleBackend{}
+	parseError := newSearchError(ErrParse, backend, "", http.StatusOK, errors.New("search input not found"))
+
+	tests := []struct {
+		name         string
+		responseType int
+		status       int
+		err          error
+		wantFailover bool
+		wantOpen     bool
+	}{
+		{name: "page", responseType: SearchResponsePage, status: http.StatusOK, wantFailover: false, wantOpen: false},
+		{name: "captcha", responseType: SearchResponseCaptcha, status: http.StatusTooManyRequests, err: newSearchError(ErrCaptcha, backend, "", http.StatusTooManyRequests, nil), wantFailover: true, wantOpen: true},
+		{name: "server error", responseType: SearchResponseError, status: http.StatusBadGateway, err: newSearchError(ErrUpstreamStatus, backend, "", http.StatusBadGateway, nil), wantFailover: true, wantOpen: true},
+		{name: "network error", responseType: SearchResponseError, err: newSearchError(ErrNetwork, backend, "", 0, errors.New("connection refused")), wantFailover: true, wantOpen: true},
+		{name: "not found", responseType: SearchResponseError, status: http.StatusNotFound, err: newSearchError(ErrUpstreamStatus, backend, "", http.StatusNotFound, nil), wantFailover: true, wantOpen: false},
+		{name: "parse error", responseType: SearchResponseError, status: http.StatusOK, err: parseError, wantFailover: true, wantOpen: false},
+		{name: "proxy error", responseType: SearchResponseProxyError, err: newSearchError(ErrProxy, backend, "", 0, errors.New("connection refused")), wantFailover: false, wantOpen: false},
+		{name: "not supported", responseType: SearchResponseError, err: ErrSearchTypeNotSupported, wantFailover: true, wantOpen: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resetBreakers(t, 1, time.Minute)
+
+			if got := isFailover(context.Background(), backend, test.responseType, test.status, test.err); got != test.wantFailover {
+				t.Errorf("got failover %t, want %t", got, test.wantFailover)
+			}
+
+			if open := !getBreaker(backend.Name()).allow(); open != test.wantOpen {
+				t.Errorf("got breaker open %t, want %t", open, test.wantOpen)
+			}
+		})
+	}
+
+	t.Run("cancelled", func(t *testing.T) {
+		resetBreakers(t, 1, time.Minute)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if isFailover(ctx, backend, SearchResponseError, 0, newSearchError(ErrNetwork, backend, "", 0, context.Canceled)) {
+			t.Error("cancelled request failed over")
+		}
+		if !getBreaker(backend.Name()).allow() {
+			t.Error("cancelled request opened the breaker")
+		}
+	})
+}
+
+// fixtureBackend is the Google backend answering every request with
+// a fixture and a status, 200 unless set
+type fixtureBackend struct {
+	GoogleBackend
+	name    string
+	fixture string
+	status  int
+}
+
+func (b *fixtureBackend) Name() string {
+	if len(b.name) > 0 {
+		return b.name
+	}
+	return b.GoogleBackend.Name()
+}
+
+func (b *fixtureBackend) Fetch(ctx context.Context, searchUrl string) (body []byte, err error, status int) {
+	status = b.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	body, err = os.ReadFile(filepath.Join("testdata", b.fixture))
+	return body, err, status
+}
+
+func TestEmptyResultsDontOpenBreaker(t *testing.T) {
+	resetBreakers(t, 3, time.Minute)
+
+	imagesBackend := &fixtureBackend{fixture: "google/images/no-results.html"}
+	videosBackend := &fixtureBackend{fixture: "google/videos/no-results.html"}
+
+	for i := 0; i < 3; i++ {
+		imagesResponse, err := imageSearchBackend(context.Background(), imagesBackend, "qwxzvbnmlkjhgf", SearchQueryParams{})
+		if err != nil || imagesResponse.Type != SearchResponsePage || len(imagesResponse.ImagesPage.ImageResults) != 0 {
+			t.Fatalf("got images response type %d, error %v, want an empty page", imagesResponse.Type, err)
+		}
+		isFailover(context.Background(), imagesBackend, imagesResponse.Type, imagesResponse.Status, err)
+
+		videosResponse, err := videoSearchBackend(context.Background(), videosBackend, "qwxzvbnmlkjhgf", SearchQueryParams{})
+		if err != nil || videosResponse.Type != SearchResponsePage || len(videosResponse.VideosPage.VideoResults) != 0 {
+			t.Fatalf("got videos response type %d, error %v, want an empty page", videosResponse.Type, err)
+		}
+		isFailover(context.Background(), videosBackend, videosResponse.Type, videosResponse.Status, err)
+	}
+
+	if !getBreaker(GoogleBackendName).allow() {
+		t.Error("searches without results opened the breaker")
+	}
+}
+
+func TestCaptchaPageWithStatus200(t *testing.T) {
+	resetBreakers(t, 3, time.Minute)
+
+	for _, name := range []string{"google/search/first-page.html", "google/images/first-page.html", "google/videos/first-page.html"} {
+		if isCaptchaPage(loadFixture(t, name)) {
+			t.Errorf("%s is detected as a captcha page", name)
+		}
+	}
+
+	backend := &fixtureBackend{fixture: "google/search/captcha.html"}
+
+	response, err := searchBackend(context.Background(), backend, "golang", SearchQueryParams{})
+	if !errors.Is(err, ErrCaptcha) || response.Type != SearchResponseCaptcha || response.Captcha == nil {
+		t.Fatalf("got response type %d, error %v, want the captcha page", response.Type, err)
+	}
+
+	if !isFailover(context.Background(), backend, response.Type, response.Status, err) {
+		t.Fatal("captcha page isn't failed over")
+	}
+
+	if getBreaker(backend.Name()).allow() {
+		t.Error("captcha pages served with status 200 didn't open the breaker")
+	}
+}
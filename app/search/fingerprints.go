@@ -0,0 +1,241 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/http/httpguts"
+	"gopkg.in/yaml.v3"
+)
+
+const fingerprintsVersion = 1
+
+// Ways of picking the header profile of a backend request
+const (
+	FingerprintPerRequest = "request"
+	FingerprintPerSession = "session"
+)
+
+//go:embed fingerprints.yaml
+var defaultFingerprints []byte
+
+// Accept-Language is derived from the search and Accept-Encoding is set by
+// the transport, which only decompresses responses when it set it itself
+var derivedHeaders = []string{"Accept-Language", "Accept-Encoding"}
+
+// Only languages like `en` or `pt-BR` are sent, hl comes from the query string
+var headerLanguagePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z]{2})?$`)
+
+// FingerprintProfile is a set of headers sent by one browser, so requests
+// look like they come from it
+type FingerprintProfile struct {
+	Name    string            `yaml:"name"`
+	Headers map[string]string `yaml:"headers"`
+}
+
+type fingerprintFile struct {
+	Version  int                  `yaml:"version"`
+	Profiles []FingerprintProfile `yaml:"profiles"`
+}
+
+var fingerprints atomic.Pointer[[]FingerprintProfile]
+
+var fingerprintRotation = FingerprintPerRequest
+var fingerprintSessionLength = 30 * time.Minute
+
+// sessions keep the profile picked for the current session of every egress
+// in the session rotation, so each proxy keeps looking like one browser
+var sessions struct {
+	mutex    sync.Mutex
+	profiles map[string]fingerprintSession
+}
+
+type fingerprintSession struct {
+	profile *FingerprintProfile
+	until   time.Time
+}
+
+func init() {
+	profiles, err := parseFingerprints(defaultFingerprints)
+	if err != nil {
+		panic(fmt.Sprintf("default fingerprints: %s", err))
+	}
+
+	fingerprints.Store(&profiles)
+}
+
+func parseFingerprints(data []byte) ([]FingerprintProfile, error) {
+	file := fingerprintFile{}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(&file); err != nil {
+		return nil, err
+	}
+
+	if file.Version != fingerprintsVersion {
+		return nil, fmt.Errorf("unsupported fingerprints version %d (%d expected)", file.Version, fingerprintsVersion)
+	}
+
+	if len(file.Profiles) == 0 {
+		return nil, errors.New("at least one profile expected")
+	}
+
+	for i, profile := range file.Profiles {
+		if len(profile.Name) == 0 {
+			return nil, fmt.Errorf("profile %d has no name", i+1)
+		}
+
+		hasUserAgent := false
+
+		for name, value := range profile.Headers {
+			if !httpguts.ValidHeaderFieldName(name) {
+				return nil, fmt.Errorf("profile %s has an invalid header name %q", profile.Name, name)
+			}
+
+			if !httpguts.ValidHeaderFieldValue(value) {
+				return nil, fmt.Errorf("profile %s has an invalid value for %s", profile.Name, name)
+			}
+
+			if strings.EqualFold(name, "User-Agent") && len(value) > 0 {
+				hasUserAgent = true
+			}
+
+			for _, derived := range derivedHeaders {
+				if strings.EqualFold(name, derived) {
+					return nil, fmt.Errorf("profile %s sets %s, which is set by sitelook", profile.Name, derived)
+				}
+			}
+		}
+
+		if !hasUserAgent {
+			return nil, fmt.Errorf("profile %s has no User-Agent header", profile.Name)
+		}
+	}
+
+	return file.Profiles, nil
+}
+
+// LoadFingerprints replaces the built-in header profiles with the ones from
+// the file. The current profiles are kept when it is invalid.
+func LoadFingerprints(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	profiles, err := parseFingerprints(data)
+	if err != nil {
+		return fmt.Errorf("fingerprints %s: %w", path, err)
+	}
+
+	fingerprints.Store(&profiles)
+
+	sessions.mutex.Lock()
+	sessions.profiles = nil
+	sessions.mutex.Unlock()
+
+	return nil
+}
+
+// SetFingerprintRotation selects whether every backend request gets a random
+// profile or one profile is used for sessionLength before the next is picked.
+func SetFingerprintRotation(rotation string, sessionLength time.Duration) error {
+	if rotation != FingerprintPerRequest && rotation != FingerprintPerSession {
+		return fmt.Errorf("unknown fingerprint rotation %q (available: %s, %s)", rotation, FingerprintPerRequest, FingerprintPerSession)
+	}
+
+	fingerprintRotation = rotation
+	fingerprintSessionLength = sessionLength
+	return nil
+}
+
+// pickFingerprint returns the profile of a request leaving through egress,
+// the host of its proxy or empty for direct requests
+func pickFingerprint(egress string) *FingerprintProfile {
+	profiles := *fingerprints.Load()
+
+	if fingerprintRotation == FingerprintPerRequest {
+		return &profiles[rand.Intn(len(profiles))]
+	}
+
+	sessions.mutex.Lock()
+	defer sessions.mutex.Unlock()
+
+	if sessions.profiles == nil {
+		sessions.profiles = map[string]fingerprintSession{}
+	}
+
+	session, ok := sessions.profiles[egress]
+	if !ok || time.Now().After(session.until) {
+		session = fingerprintSession{
+			profile: &profiles[rand.Intn(len(profiles))],
+			until:   time.Now().Add(fingerprintSessionLength),
+		}
+		sessions.profiles[egress] = session
+	}
+
+	return session.profile
+}
+
+type languageKey struct{}
+
+// withLanguage stores the interface language of a search in its context, so
+// backend requests ask for pages in it
+func withLanguage(ctx context.Context, language string) context.Context {
+	return context.WithValue(ctx, languageKey{}, language)
+}
+
+// acceptLanguage lists the language of the search before English like
+// a browser set to it would, e.g. `pt-BR,pt;q=0.9,en;q=0.8`
+func acceptLanguage(language string) string {
+	if !headerLanguagePattern.MatchString(language) {
+		return "en-US,en;q=0.9"
+	}
+
+	base, region, hasRegion := strings.Cut(language, "-")
+	if base == "en" {
+		if hasRegion {
+			return fmt.Sprintf("en-%s,en;q=0.9", strings.ToUpper(region))
+		}
+		return "en-US,en;q=0.9"
+	}
+
+	if hasRegion {
+		return fmt.Sprintf("%s-%s,%s;q=0.9,en;q=0.8", base, strings.ToUpper(region), base)
+	}
+	return fmt.Sprintf("%s,en;q=0.9", base)
+}
+
+// browserHeader returns the headers of the fingerprint profile of egress with
+// the Accept-Language of the search in ctx. The backend's own headers are set
+// over them.
+func browserHeader(ctx context.Context, egress string, backendHeader http.Header) http.Header {
+	profile := pickFingerprint(egress)
+
+	header := http.Header{}
+	for name, value := range profile.Headers {
+		header.Set(name, value)
+	}
+
+	language, _ := ctx.Value(languageKey{}).(string)
+	header.Set("Accept-Language", acceptLanguage(language))
+
+	for name, values := range backendHeader {
+		header[name] = values
+	}
+
+	return header
+}
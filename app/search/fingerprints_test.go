@@ -0,0 +1,170 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		language string
+		want     string
+	}{
+		{language: "", want: "en-US,en;q=0.9"},
+		{language: "en", want: "en-US,en;q=0.9"},
+		{language: "en-gb", want: "en-GB,en;q=0.9"},
+		{language: "de", want: "de,en;q=0.9"},
+		{language: "pt-BR", want: "pt-BR,pt;q=0.9,en;q=0.8"},
+		{language: "fil", want: "fil,en;q=0.9"},
+		{language: "pt_BR", want: "en-US,en;q=0.9"},
+		{language: "de\r\nX-Injected: 1", want: "en-US,en;q=0.9"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.language, func(t *testing.T) {
+			if got := acceptLanguage(test.language); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseFingerprints(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			file: "version: 1\nprofiles:\n  - name: firefox\n    headers:\n      User-Agent: Mozilla/5.0\n      Accept: text/html\n",
+		},
+		{
+			name:    "unsupported version",
+			file:    "version: 2\nprofiles:\n  - name: firefox\n    headers:\n      User-Agent: Mozilla/5.0\n",
+			wantErr: "unsupported fingerprints version 2",
+		},
+		{
+			name:    "unknown field",
+			file:    "version: 1\nprofiles:\n  - name: firefox\n    header:\n      User-Agent: Mozilla/5.0\n",
+			wantErr: "field header not found",
+		},
+		{
+			name:    "no profiles",
+			file:    "version: 1\nprofiles: []\n",
+			wantErr: "at least one profile expected",
+		},
+		{
+			name:    "profile without a name",
+			file:    "version: 1\nprofiles:\n  - headers:\n      User-Agent: Mozilla/5.0\n",
+			wantErr: "profile 1 has no name",
+		},
+		{
+			name:    "profile without a user agent",
+			file:    "version: 1\nprofiles:\n  - name: firefox\n    headers:\n      Accept: text/html\n",
+			wantErr: "profile firefox has no User-Agent header",
+		},
+		{
+			name:    "derived header",
+			file:    "version: 1\nprofiles:\n  - name: firefox\n    headers:\n      User-Agent: Mozilla/5.0\n      accept-language: de\n",
+			wantErr: "profile firefox sets Accept-Language",
+		},
+		{
+			name:    "invalid header name",
+			file:    "version: 1\nprofiles:\n  - name: firefox\n    headers:\n      User-Agent: Mozilla/5.0\n      \"Sec Fetch Mode\": navigate\n",
+			wantErr: `profile firefox has an invalid header name "Sec Fetch Mode"`,
+		},
+		{
+			name:    "invalid header value",
+			file:    "version: 1\nprofiles:\n  - name: firefox\n    headers:\n      User-Agent: \"Mozilla/5.0\\r\\nX-Injected: 1\"\n",
+			wantErr: "profile firefox has an invalid value for User-Agent",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			profiles, err := parseFingerprints([]byte(test.file))
+
+			if len(test.wantErr) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(profiles) != 1 || profiles[0].Name != "firefox" {
+					t.Errorf("got profiles %+v, want the firefox profile", profiles)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("got error %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestDefaultFingerprints(t *testing.T) {
+	if _, err := parseFingerprints(defaultFingerprints); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSessionFingerprintPerEgress(t *testing.T) {
+	previousProfiles := fingerprints.Load()
+	t.Cleanup(func() {
+		fingerprints.Store(previousProfiles)
+		SetFingerprintRotation(FingerprintPerRequest, 30*time.Minute)
+		sessions.profiles = nil
+	})
+
+	profiles := []FingerprintProfile{}
+	for _, agent := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		profiles = append(profiles, FingerprintProfile{Name: agent, Headers: map[string]string{"User-Agent": agent}})
+	}
+	fingerprints.Store(&profiles)
+	sessions.profiles = nil
+
+	if err := SetFingerprintRotation(FingerprintPerSession, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	egresses := []string{""}
+	for i := 1; i <= 20; i++ {
+		egresses = append(egresses, fmt.Sprintf("proxy%d:3128", i))
+	}
+	picked := map[string]bool{}
+
+	for _, egress := range egresses {
+		profile := pickFingerprint(egress)
+		picked[profile.Name] = true
+
+		for i := 0; i < 5; i++ {
+			if again := pickFingerprint(egress); again != profile {
+				t.Fatalf("egress %q got profile %s, then %s within the session", egress, profile.Name, again.Name)
+			}
+		}
+	}
+
+	// 21 egresses picking the same of eight profiles by chance is unlikely
+	if len(picked) < 2 {
+		t.Errorf("every egress got the same profile, want a session per egress")
+	}
+}
+
+func TestBrowserHeader(t *testing.T) {
+	ctx := withLanguage(context.Background(), "de")
+	header := browserHeader(ctx, "", http.Header{"Accept": {"application/json"}})
+
+	if got := header.Get("Accept"); got != "application/json" {
+		t.Errorf("got Accept %q, want the backend's header", got)
+	}
+	if got := header.Get("Accept-Language"); got != "de,en;q=0.9" {
+		t.Errorf("got Accept-Language %q, want the search's language", got)
+	}
+	if len(header.Get("User-Agent")) == 0 {
+		t.Errorf("got no User-Agent, want the profile's")
+	}
+}
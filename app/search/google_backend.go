@@ -0,0 +1,121 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const GoogleBackendName = "google"
+
+var googleUrl = "https://google.com"
+
+type GoogleBackend struct{}
+
+// SetGoogleUrl selects the Google instance searches are sent to, e.g. a
+// country domain
+func SetGoogleUrl(instanceUrl string) error {
+	parsedUrl, err := url.Parse(instanceUrl)
+	if err != nil {
+		return err
+	}
+
+	if parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https" {
+		return fmt.Errorf("google url %q must be an http or https url", instanceUrl)
+	}
+
+	googleUrl = strings.TrimSuffix(instanceUrl, "/")
+	return nil
+}
+
+func (b *GoogleBackend) Name() string {
+	return GoogleBackendName
+}
+
+func (b *GoogleBackend) Title() string {
+	return "Google"
+}
+
+func (b *GoogleBackend) SearchUrl(searchTerm string, searchType string, params SearchQueryParams) (string, error) {
+	return getSearchUrl(searchTerm, params.Start, searchType, params.SearchLanguage, params.InterfaceLanguage), nil
+}
+
+func (b *GoogleBackend) Fetch(ctx context.Context, searchUrl string) (body []byte, err error, status int) {
+	return getDocument(ctx, b.Name(), searchUrl, nil)
+}
+
+func (b *GoogleBackend) ParseSearchPage(body []byte, params SearchQueryParams) (*SearchPage, error) {
+	document, err := newDocument(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSearchPage(document, params.Start)
+}
+
+func (b *GoogleBackend) ParseImagesPage(body []byte, params SearchQueryParams) (ImagesPage, error) {
+	document, err := newDocument(body)
+	if err != nil {
+		return ImagesPage{}, err
+	}
+
+	return parseImagesPage(document)
+}
+
+func (b *GoogleBackend) ParseVideosPage(body []byte, params SearchQueryParams) (VideosPage, error) {
+	document, err := newDocument(body)
+	if err != nil {
+		return VideosPage{}, err
+	}
+
+	return parseVideosPage(document)
+}
+
+func getSearchUrl(searchTerm string, start int, searchType string, searchLang string, interfaceLang string) string {
+	searchUrl, _ := url.Parse(googleUrl + "/search")
+	query := searchUrl.Query()
+
+	query.Add("q", searchTerm)
+
+	if start > 0 {
+		query.Add("start", strconv.Itoa(start))
+	}
+
+	if len(searchType) > 0 {
+		query.Add("tbm", searchType)
+	}
+
+	if len(searchLang) > 0 {
+		query.Add("lr", searchLang)
+	}
+
+	if len(interfaceLang) > 0 {
+		query.Add("hl", interfaceLang)
+	}
+
+	// non-js version
+	query.Set("gbv", "1")
+
+	searchUrl.RawQuery = query.Encode()
+	return searchUrl.String()
+}
+
+func (b *GoogleBackend) SuggestionsUrl(searchTerm string, params SearchQueryParams) string {
+	suggestionsUrl, _ := url.Parse("https://suggestqueries.google.com/complete/search")
+	query := suggestionsUrl.Query()
+
+	query.Add("q", searchTerm)
+	// OpenSearch suggestions format
+	query.Add("client", "firefox")
+	query.Add("ie", "utf-8")
+	query.Add("oe", "utf-8")
+
+	if len(params.InterfaceLanguage) > 0 {
+		query.Add("hl", params.InterfaceLanguage)
+	}
+
+	suggestionsUrl.RawQuery = query.Encode()
+	return suggestionsUrl.String()
+}
@@ -0,0 +1,101 @@
+package search
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+var updateGoldenFiles = flag.Bool("update", false, "rewrite golden files with the current parser output")
+
+// goldenOutput is what a golden file holds for a fixture: the parsed page and
+// the error returned by the parser
+type goldenOutput struct {
+	Page  interface{} `json:"page"`
+	Error string      `json:"error,omitempty"`
+}
+
+// testGoldenFiles parses every `.html` fixture in testdata/<dir> and compares
+// the output with the `.golden.json` file next to it. Golden files are
+// rewritten with `go test ./app/search -run Golden -update`.
+func testGoldenFiles(t *testing.T, dir string, parse func(name string, document *goquery.Document) (interface{}, error)) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", dir, "*.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fixtures) == 0 {
+		t.Fatalf("no fixtures found in testdata/%s", dir)
+	}
+
+	for _, fixture := range fixtures {
+		fixture := fixture
+		name := strings.TrimSuffix(filepath.Base(fixture), ".html")
+
+		t.Run(name, func(t *testing.T) {
+			document := loadFixture(t, filepath.Join(dir, name+".html"))
+
+			page, err := parse(name, document)
+			output := goldenOutput{Page: page}
+			if err != nil {
+				output.Error = err.Error()
+			}
+
+			got, err := json.MarshalIndent(output, "", "    ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			goldenFile := strings.TrimSuffix(fixture, ".html") + ".golden.json"
+
+			if *updateGoldenFiles {
+				if err := os.WriteFile(goldenFile, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(goldenFile)
+			if err != nil {
+				t.Fatalf("%s (run with -update to create it)", err)
+			}
+
+			if !bytes.Equal(got, want) {
+				t.Errorf("output differs from %s (run with -update if the change is intended)\ngot:\n%s\nwant:\n%s", goldenFile, got, want)
+			}
+		})
+	}
+}
+
+// Offsets the fixtures were requested with, the others are first pages
+var googleSearchFixtureStarts = map[string]int{
+	"second-page":          10,
+	"second-and-last-page": 10,
+	"middle-page":          40,
+	"last-page":            180,
+}
+
+func TestGoogleSearchPageGoldenFiles(t *testing.T) {
+	testGoldenFiles(t, "google/search", func(name string, document *goquery.Document) (interface{}, error) {
+		return parseSearchPage(document, googleSearchFixtureStarts[name])
+	})
+}
+
+func TestGoogleImagesPageGoldenFiles(t *testing.T) {
+	testGoldenFiles(t, "google/images", func(name string, document *goquery.Document) (interface{}, error) {
+		return parseImagesPage(document)
+	})
+}
+
+func TestGoogleVideosPageGoldenFiles(t *testing.T) {
+	testGoldenFiles(t, "google/videos", func(name string, document *goquery.Document) (interface{}, error) {
+		return parseVideosPage(document)
+	})
+}
@@ -0,0 +1,258 @@
+package search
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"sitelook/app/metrics"
+)
+
+// Parsers are named after the backend and the search type e.g. `google/web`
+const (
+	webParser    = "web"
+	imagesParser = "images"
+	videosParser = "videos"
+)
+
+// Saved pages are capped so a broken parser doesn't fill the disk
+const maxParserDumps = 100
+
+type ParserStats struct {
+	Name             string
+	Pages            uint64
+	EmptyPages       uint64 // pages without results
+	Results          uint64
+	SkippedResults   uint64 // results left out because of unexpected markup
+	Errors           uint64 // pages the parser returned an error for
+	PaginationErrors uint64
+	MissingFields    map[string]uint64
+	LastError        string
+	LastErrorAt      time.Time
+	EmptyRate        float64 // share of empty pages among the recent ones
+	Broken           bool
+}
+
+type parserHealth struct {
+	mutex  sync.Mutex
+	stats  ParserStats
+	recent []bool // whether the recent pages were empty, used as a ring buffer
+	next   int
+}
+
+var parserHealthsMutex sync.Mutex
+var parserHealths = make(map[string]*parserHealth)
+
+// A parser is considered broken when at least breakageThreshold of the last
+// breakageWindow pages had no results. Search terms without results are rare
+// enough for this to indicate changed markup.
+var breakageThreshold = 0.5
+var breakageWindow = 20
+
+var parserDumpDir = ""
+var parserDumpsMutex sync.Mutex
+var parserDumps = 0
+
+func SetBreakageDetector(threshold float64, window int) error {
+	if threshold <= 0 || threshold > 1 {
+		return errors.New("breakage threshold must be between 0 and 1")
+	}
+
+	if window <= 0 {
+		return errors.New("breakage window must be positive")
+	}
+
+	parserHealthsMutex.Lock()
+	defer parserHealthsMutex.Unlock()
+
+	breakageThreshold = threshold
+	breakageWindow = window
+	parserHealths = make(map[string]*parserHealth)
+	return nil
+}
+
+// SetParserDumpDir saves pages which parsers returned no results or an error
+// for into dir, an empty dir disables it
+func SetParserDumpDir(dir string) error {
+	if len(dir) > 0 {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	parserDumpDir = dir
+	return nil
+}
+
+func parserName(backendName string, parser string) string {
+	return backendName + "/" + parser
+}
+
+func getParserHealth(name string) *parserHealth {
+	parserHealthsMutex.Lock()
+	defer parserHealthsMutex.Unlock()
+
+	health, ok := parserHealths[name]
+	if !ok {
+		health = &parserHealth{
+			stats:  ParserStats{Name: name, MissingFields: make(map[string]uint64)},
+			recent: make([]bool, 0, breakageWindow),
+		}
+		parserHealths[name] = health
+	}
+
+	return health
+}
+
+// recordParsedPage counts a page parsed for a non-empty search term and
+// updates the breakage detector
+func recordParsedPage(name string, pageUrl string, body []byte, status int, results int, err error) {
+	health := getParserHealth(name)
+	isEmpty := results == 0
+
+	health.mutex.Lock()
+
+	health.stats.Pages++
+	health.stats.Results += uint64(results)
+
+	if isEmpty {
+		health.stats.EmptyPages++
+	}
+
+	if err != nil {
+		metrics.CountParseError(name)
+		health.stats.Errors++
+		health.stats.LastError = err.Error()
+		health.stats.LastErrorAt = time.Now()
+	}
+
+	if len(health.recent) < cap(health.recent) {
+		health.recent = append(health.recent, isEmpty)
+	} else {
+		health.recent[health.next] = isEmpty
+		health.next = (health.next + 1) % len(health.recent)
+	}
+
+	emptyPages := 0
+	for _, wasEmpty := range health.recent {
+		if wasEmpty {
+			emptyPages++
+		}
+	}
+
+	recentPages := len(health.recent)
+	wasBroken := health.stats.Broken
+	health.stats.EmptyRate = float64(emptyPages) / float64(recentPages)
+	health.stats.Broken = recentPages == cap(health.recent) && health.stats.EmptyRate >= breakageThreshold
+	emptyRate, isBroken := health.stats.EmptyRate, health.stats.Broken
+
+	health.mutex.Unlock()
+
+	metrics.SetParserHealth(name, emptyRate, isBroken)
+
+	if isBroken && !wasBroken {
+		slog.Error("parser looks broken", "parser", name, "emptyPages", emptyPages, "recentPages", recentPages)
+	} else if !isBroken && wasBroken {
+		slog.Info("parser recovered", "parser", name)
+	}
+
+	if isEmpty || err != nil {
+		dumpPage(name, pageUrl, body, status)
+	}
+}
+
+func recordPaginationError(name string, err error) {
+	health := getParserHealth(name)
+
+	health.mutex.Lock()
+	defer health.mutex.Unlock()
+
+	health.stats.PaginationErrors++
+	health.stats.LastError = err.Error()
+	health.stats.LastErrorAt = time.Now()
+}
+
+func recordMissingField(name string, field string) {
+	health := getParserHealth(name)
+
+	health.mutex.Lock()
+	defer health.mutex.Unlock()
+
+	health.stats.MissingFields[field]++
+}
+
+// recordSkippedResult counts a result left out because the field is missing
+// or doesn't look as expected
+func recordSkippedResult(name string, field string) {
+	health := getParserHealth(name)
+
+	health.mutex.Lock()
+	defer health.mutex.Unlock()
+
+	health.stats.SkippedResults++
+	health.stats.MissingFields[field]++
+}
+
+func GetParserStats() []ParserStats {
+	parserHealthsMutex.Lock()
+	healths := make([]*parserHealth, 0, len(parserHealths))
+	for _, health := range parserHealths {
+		healths = append(healths, health)
+	}
+	parserHealthsMutex.Unlock()
+
+	stats := make([]ParserStats, 0, len(healths))
+
+	for _, health := range healths {
+		health.mutex.Lock()
+		parserStats := health.stats
+		parserStats.MissingFields = make(map[string]uint64, len(health.stats.MissingFields))
+		for field, count := range health.stats.MissingFields {
+			parserStats.MissingFields[field] = count
+		}
+		health.mutex.Unlock()
+
+		stats = append(stats, parserStats)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+
+	return stats
+}
+
+func dumpPage(name string, pageUrl string, body []byte, status int) {
+	dir := parserDumpDir
+	if len(dir) == 0 {
+		return
+	}
+
+	parserDumpsMutex.Lock()
+	if parserDumps >= maxParserDumps {
+		parserDumpsMutex.Unlock()
+		return
+	}
+	parserDumps++
+	if parserDumps == maxParserDumps {
+		slog.Warn("parser dump limit reached, no more pages are saved until a restart", "pages", maxParserDumps, "dir", dir)
+	}
+	parserDumpsMutex.Unlock()
+
+	meta := responseMeta{
+		Url:       pageUrl,
+		Status:    status,
+		Size:      int64(len(body)),
+		FetchedAt: time.Now(),
+	}
+
+	path := filepath.Join(dir, strings.ReplaceAll(name, "/", "-")+"-"+diskCacheName(pageUrl))
+	if err := writeResponse(path, meta, body); err != nil {
+		slog.Error("parser dump failed", "error", err)
+	}
+}
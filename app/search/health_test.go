@@ -0,0 +1,97 @@
+package search
+
+import (
+	"testing"
+)
+
+// useBreakageDetector sets up the breakage detector of a test and restores
+// the defaults afterwards
+func useBreakageDetector(t *testing.T, threshold float64, window int) {
+	t.Helper()
+
+	previousThreshold, previousWindow := breakageThreshold, breakageWindow
+	if err := SetBreakageDetector(threshold, window); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { SetBreakageDetector(previousThreshold, previousWindow) })
+}
+
+func getParserStats(t *testing.T, name string) ParserStats {
+	t.Helper()
+
+	for _, stats := range GetParserStats() {
+		if stats.Name == name {
+			return stats
+		}
+	}
+
+	t.Fatalf("no stats for parser %s", name)
+	return ParserStats{}
+}
+
+func TestBreakageDetector(t *testing.T) {
+	useBreakageDetector(t, 0.5, 4)
+	name := parserName("stand-in", webParser)
+
+	// each page replaces the oldest one once the window is full
+	pages := []struct {
+		results       int
+		wantEmptyRate float64
+		wantBroken    bool
+	}{
+		{results: 0, wantEmptyRate: 1},
+		{results: 0, wantEmptyRate: 1},
+		{results: 10, wantEmptyRate: 2.0 / 3},
+		// the window is full with half of the pages empty
+		{results: 10, wantEmptyRate: 0.5, wantBroken: true},
+		// the first empty page leaves the window
+		{results: 10, wantEmptyRate: 0.25},
+		{results: 0, wantEmptyRate: 0.25},
+		{results: 0, wantEmptyRate: 0.5, wantBroken: true},
+		{results: 0, wantEmptyRate: 0.75, wantBroken: true},
+		{results: 10, wantEmptyRate: 0.75, wantBroken: true},
+		{results: 10, wantEmptyRate: 0.5, wantBroken: true},
+		{results: 10, wantEmptyRate: 0.25},
+	}
+
+	for i, page := range pages {
+		recordParsedPage(name, "https://backend.example/search?q=golang", nil, 200, page.results, nil)
+
+		stats := getParserStats(t, name)
+		if stats.EmptyRate != page.wantEmptyRate || stats.Broken != page.wantBroken {
+			t.Errorf("page %d: got empty rate %.2f and broken %t, want %.2f and %t", i+1, stats.EmptyRate, stats.Broken, page.wantEmptyRate, page.wantBroken)
+		}
+	}
+
+	stats := getParserStats(t, name)
+	if stats.Pages != uint64(len(pages)) || stats.EmptyPages != 5 || stats.Results != 60 {
+		t.Errorf("got %d pages, %d empty pages and %d results, want %d, 5 and 60", stats.Pages, stats.EmptyPages, stats.Results, len(pages))
+	}
+}
+
+func TestBreakageDetectorCountsErrorsAsEmpty(t *testing.T) {
+	useBreakageDetector(t, 1, 2)
+	name := parserName("stand-in", imagesParser)
+
+	recordParsedPage(name, "https://backend.example/images?q=golang", nil, 200, 0, ErrParse)
+	recordParsedPage(name, "https://backend.example/images?q=golang", nil, 200, 0, ErrParse)
+
+	stats := getParserStats(t, name)
+	if !stats.Broken || stats.Errors != 2 || stats.LastError != ErrParse.Error() {
+		t.Errorf("got broken %t, %d errors and last error %q, want a broken parser with 2 errors", stats.Broken, stats.Errors, stats.LastError)
+	}
+}
+
+func TestSetBreakageDetectorErrors(t *testing.T) {
+	useBreakageDetector(t, breakageThreshold, breakageWindow)
+
+	for _, detector := range []struct {
+		threshold float64
+		window    int
+	}{{0, 20}, {1.5, 20}, {0.5, 0}} {
+		if err := SetBreakageDetector(detector.threshold, detector.window); err == nil {
+			t.Errorf("threshold %.1f and window %d were accepted", detector.threshold, detector.window)
+		}
+	}
+}
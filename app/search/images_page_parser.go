@@ -3,7 +3,7 @@ package search
 import (
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/url"
 
 	"github.com/PuerkitoBio/goquery"
@@ -40,14 +40,14 @@ func hrefFromQuery(url_ string) string {
 	return query
 }
 
-func parseImagePagePagination(document *goquery.Document) (SinglePagePagination, error) {
-	paginationTable := findSingle(document.Selection, "body > table")
+func parseImagePagePagination(document *goquery.Document, selectors ImagesPageSelectors) (SinglePagePagination, error) {
+	paginationTable := findFirst(document.Selection, selectors.Pagination)
 
 	if selectionEmpty(paginationTable) {
 		return SinglePagePagination{}, errors.New("pagination not found")
 	}
 
-	tds := paginationTable.Find("td")
+	tds := findAll(paginationTable, selectors.PaginationCells)
 
 	pagination := SinglePagePagination{
 		PreviousLinkPresent: false,
@@ -67,13 +67,13 @@ func parseImagePagePagination(document *goquery.Document) (SinglePagePagination,
 
 		if len(containers) == 1 {
 			pagination.NextLinkPresent = true
-			pagination.NextOffset, _ = getOffsetFromHref(findSingle(tds, "a").AttrOr("href", "#"))
+			pagination.NextOffset, _ = getOffsetFromHref(findFirst(tds, selectors.PaginationLink).AttrOr("href", "#"))
 		} else if len(containers) == 5 {
-			previousLink := findSingle(containers[1], "a")
+			previousLink := findFirst(containers[1], selectors.PaginationLink)
 			pagination.PreviousLinkPresent = !selectionEmpty(previousLink)
 			pagination.PreviousOffset, _ = getOffsetFromHref(previousLink.AttrOr("href", "#"))
 			pagination.CurrentTitle = containers[2].Text()
-			nextLink := findSingle(containers[3], "a")
+			nextLink := findFirst(containers[3], selectors.PaginationLink)
 			pagination.NextLinkPresent = !selectionEmpty(nextLink)
 			nextOffset, isSet := getOffsetFromHref(nextLink.AttrOr("href", "#"))
 			if isSet {
@@ -97,9 +97,9 @@ func selectionToArray(selection *goquery.Selection) []*goquery.Selection {
 	return elements
 }
 
-func parseVideoPagePagination(document *goquery.Document) (SinglePagePagination, error) {
-	footer := findSingle(document.Selection, "footer > div")
-	paginationDiv := findSingle(footer, "div > a").Parent()
+func parseVideoPagePagination(document *goquery.Document, selectors VideosPageSelectors) (SinglePagePagination, error) {
+	footer := findFirst(document.Selection, selectors.PaginationFooter)
+	paginationDiv := findFirst(footer, selectors.PaginationLink).Parent()
 
 	if selectionEmpty(paginationDiv) {
 		return SinglePagePagination{}, errors.New("pagination not found")
@@ -113,8 +113,8 @@ func parseVideoPagePagination(document *goquery.Document) (SinglePagePagination,
 		CurrentTitle:        "",
 	}
 
-	links := selectionToArray(paginationDiv.Find("a"))
-	span := findSingle(paginationDiv, "div > span")
+	links := selectionToArray(findAll(paginationDiv, selectors.PaginationLinks))
+	span := findFirst(paginationDiv, selectors.PaginationCurrent)
 
 	if len(links) == 0 {
 		return pagination, errors.New("pagination links not found")
@@ -160,9 +160,15 @@ func parseVideoPagePagination(document *goquery.Document) (SinglePagePagination,
 }
 
 func parseImagesPage(document *goquery.Document) (ImagesPage, error) {
-	searchInput := findSingle(document.Selection, "input[name=\"q\"]")
+	if isCaptchaPage(document) {
+		return ImagesPage{}, ErrCaptcha
+	}
+
+	selectors := getSelectorProfile().Images
+	searchInput := findFirst(document.Selection, selectors.SearchInput)
 
 	if selectionEmpty(searchInput) {
+		recordMissingField(googleImagesParser, "search input")
 		return ImagesPage{}, errors.New("search input not found")
 	}
 
@@ -170,17 +176,18 @@ func parseImagesPage(document *goquery.Document) (ImagesPage, error) {
 
 	imageResults := make([]ImageResult, 0)
 
-	document.Find("tbody").Each(func(i int, tbody *goquery.Selection) {
-		image := tbody.Find("img")
+	findAll(document.Selection, selectors.Result).Each(func(i int, tbody *goquery.Selection) {
+		image := findAll(tbody, selectors.ResultImage)
 		if image.Length() != 1 {
 			return
 		}
 
 		imageSrc := image.AttrOr("src", "#")
 
-		links := tbody.Find("a")
+		links := findAll(tbody, selectors.ResultLinks)
 		if links.Length() != 2 {
-			log.Fatalf("image result element has %d links instead of 2", links.Length())
+			slog.Debug("image result skipped", "parser", googleImagesParser, "links", links.Length())
+			recordSkippedResult(googleImagesParser, "result links")
 			return
 		}
 
@@ -188,9 +195,10 @@ func parseImagesPage(document *goquery.Document) (ImagesPage, error) {
 		imageLinkHref := hrefFromQuery(links.First().AttrOr("href", "#"))
 		titleLinkHref := hrefFromQuery(links.Last().AttrOr("href", "#"))
 
-		spans := tbody.Find("a span > span")
+		spans := findAll(tbody, selectors.ResultTitles)
 		if spans.Length() != 2 {
-			log.Fatalf("image element has %d spans instead of 2", spans.Length())
+			slog.Debug("image result skipped", "parser", googleImagesParser, "spans", spans.Length())
+			recordSkippedResult(googleImagesParser, "result titles")
 			return
 		}
 
@@ -206,16 +214,19 @@ func parseImagesPage(document *goquery.Document) (ImagesPage, error) {
 		})
 	})
 
+	// a page without results and pagination is a search which found nothing,
+	// parser health reports a parser which stopped finding results
 	if len(imageResults) == 0 {
 		return ImagesPage{
 			SearchTerm:   searchTerm,
 			ImageResults: imageResults,
-		}, errors.New("page has no images or an error occured while parsing images")
+		}, nil
 	}
 
-	pagination, err := parseImagePagePagination(document)
+	pagination, err := parseImagePagePagination(document, selectors)
 	if err != nil {
-		log.Println(err)
+		slog.Debug("pagination error", "parser", googleImagesParser, "error", err)
+		recordPaginationError(googleImagesParser, err)
 	}
 
 	return ImagesPage{
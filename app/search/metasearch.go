@@ -0,0 +1,283 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MetasearchBackendName selects the metasearch mode instead of a single backend.
+const MetasearchBackendName = "meta"
+
+// Ranking constant of the reciprocal rank fusion. Higher values lower the
+// impact of a result being ranked first by a single backend.
+const reciprocalRankConstant = 60
+
+// Approximate number of results on a page, used to compute the metasearch
+// pagination offsets
+const metasearchPageSize = 10
+
+var metasearchBackendNames = []string{GoogleBackendName, DuckDuckGoBackendName, BingBackendName}
+var metasearchTimeout = 5 * time.Second
+
+type backendSearchResult struct {
+	backendName string
+	response    SearchResponse
+	err         error
+}
+
+type mergedSearchResult struct {
+	result     SearchResult
+	score      float64
+	firstIndex int
+}
+
+// pageSizeBackend is implemented by backends whose pages don't hold about
+// metasearchPageSize results
+type pageSizeBackend interface {
+	PageSize() int
+}
+
+// SetMetasearchBackends selects backends queried in the metasearch mode. The
+// first one also serves image and video searches.
+func SetMetasearchBackends(names []string) error {
+	if len(names) == 0 {
+		return errors.New("metasearch requires at least one backend")
+	}
+
+	for _, name := range names {
+		if _, ok := backends[name]; !ok {
+			return fmt.Errorf("unknown metasearch backend %q (available: %v)", name, BackendNames())
+		}
+	}
+
+	metasearchBackendNames = names
+	return nil
+}
+
+// SetMetasearchTimeout sets how long the metasearch waits for backends.
+// Results of slower backends are discarded.
+func SetMetasearchTimeout(timeout time.Duration) {
+	metasearchTimeout = timeout
+}
+
+func isMetasearch(backendName string) bool {
+	if len(backendName) == 0 {
+		backendName = defaultBackendName
+	}
+
+	return backendName == MetasearchBackendName
+}
+
+func Metasearch(ctx context.Context, searchTerm string, params SearchQueryParams) (SearchResponse, error) {
+	// backends which don't respond in time are cancelled
+	ctx, cancel := context.WithTimeout(ctx, metasearchTimeout)
+	defer cancel()
+
+	results := make(chan backendSearchResult, len(metasearchBackendNames))
+	requestCount := 0
+
+	for _, name := range metasearchBackendNames {
+		// backends with an open circuit breaker are left out
+		if !getBreaker(name).allow() {
+			continue
+		}
+
+		requestCount++
+		go func(backend Backend) {
+			response, err := searchBackend(ctx, backend, searchTerm, metasearchBackendParams(backend, params))
+			isFailover(ctx, backend, response.Type, response.Status, err)
+			results <- backendSearchResult{backendName: backend.Name(), response: response, err: err}
+		}(backends[name])
+	}
+
+	backendResults := make(map[string]backendSearchResult)
+
+collect:
+	for len(backendResults) < requestCount {
+		select {
+		case result := <-results:
+			backendResults[result.backendName] = result
+		case <-ctx.Done():
+			slog.Warn("metasearch timed out", "timeout", metasearchTimeout, "responses", len(backendResults), "requests", requestCount)
+			break collect
+		}
+	}
+
+	// keeping the configured order of backends
+	pages := []backendSearchResult{}
+	var failed *backendSearchResult
+
+	for _, name := range metasearchBackendNames {
+		result, ok := backendResults[name]
+		if !ok {
+			continue
+		}
+
+		if result.err == nil && result.response.Type == SearchResponsePage {
+			pages = append(pages, result)
+			continue
+		}
+
+		if result.err != nil {
+			slog.Warn("metasearch backend failed", "backend", name, "error", result.err)
+		}
+
+		if failed == nil || (failed.response.Type != SearchResponseCaptcha && result.response.Type == SearchResponseCaptcha) {
+			failed = &result
+		}
+	}
+
+	if len(pages) == 0 {
+		if failed != nil {
+			failed.response.Backend = MetasearchBackendName
+			return failed.response, failed.err
+		}
+		return SearchResponse{Type: SearchResponseError, Status: 0, Backend: MetasearchBackendName}, errors.New("no metasearch backend responded in time or every backend is skipped")
+	}
+
+	searchPage := mergeSearchPages(searchTerm, params.Start, pages)
+	return SearchResponse{Type: SearchResponsePage, SearchPage: &searchPage, Status: pages[0].response.Status, Backend: MetasearchBackendName}, nil
+}
+
+// metasearchBackendParams requests the page of a backend matching the
+// metasearch page, so backends with larger pages aren't asked for results
+// they already returned on the previous page
+func metasearchBackendParams(backend Backend, params SearchQueryParams) SearchQueryParams {
+	pageSize := metasearchPageSize
+	if sizedBackend, ok := backend.(pageSizeBackend); ok {
+		pageSize = sizedBackend.PageSize()
+	}
+
+	params.Start = params.Start / metasearchPageSize * pageSize
+	return params
+}
+
+func mergeSearchPages(searchTerm string, start int, pages []backendSearchResult) SearchPage {
+	merged := make(map[string]*mergedSearchResult)
+	correction := SearchCorrection{Present: false}
+	answers := []string{}
+	suggestions := []string{}
+	nextLinkPresent := false
+
+	for _, page := range pages {
+		searchPage := page.response.SearchPage
+
+		if !correction.Present && searchPage.SearchCorrection.Present {
+			correction = searchPage.SearchCorrection
+		}
+
+		if len(answers) == 0 {
+			answers = searchPage.Answers
+		}
+
+		if len(suggestions) == 0 {
+			suggestions = searchPage.Suggestions
+		}
+
+		if searchPage.Pagination.NextLinkPresent {
+			nextLinkPresent = true
+		}
+
+		// variants of a url found by the same backend only count with their
+		// best rank, which is the first one
+		seen := make(map[string]bool)
+
+		for rank, result := range searchPage.SearchResults {
+			key := normalizeResultUrl(result.Url)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			score := 1.0 / float64(reciprocalRankConstant+rank+1)
+
+			existing, ok := merged[key]
+			if !ok {
+				result.Engines = []string{page.backendName}
+				merged[key] = &mergedSearchResult{result: result, score: score, firstIndex: len(merged)}
+				continue
+			}
+
+			existing.score += score
+			existing.result.Engines = append(existing.result.Engines, page.backendName)
+			if len(existing.result.Description) == 0 {
+				existing.result.Description = result.Description
+			}
+		}
+	}
+
+	sorted := make([]*mergedSearchResult, 0, len(merged))
+	for _, result := range merged {
+		sorted = append(sorted, result)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].score != sorted[j].score {
+			return sorted[i].score > sorted[j].score
+		}
+		return sorted[i].firstIndex < sorted[j].firstIndex
+	})
+
+	searchResults := make([]SearchResult, len(sorted))
+	for i, result := range sorted {
+		searchResults[i] = result.result
+	}
+
+	pagination := SinglePagePagination{
+		PreviousLinkPresent: start > 0,
+		PreviousOffset:      0,
+		NextLinkPresent:     nextLinkPresent,
+		NextOffset:          start + metasearchPageSize,
+		CurrentTitle:        "",
+	}
+
+	if start > metasearchPageSize {
+		pagination.PreviousOffset = start - metasearchPageSize
+	}
+
+	if start > 0 {
+		pagination.CurrentTitle = fmt.Sprint(start/metasearchPageSize + 1)
+	}
+
+	return SearchPage{
+		SearchTerm:       searchTerm,
+		SearchResults:    searchResults,
+		Pagination:       pagination,
+		SearchCorrection: correction,
+		Answers:          answers,
+		Suggestions:      suggestions,
+	}
+}
+
+// normalizeResultUrl makes urls pointing to the same page from different
+// backends comparable, e.g. `http://www.example.com/page/` and
+// `https://example.com/page`
+func normalizeResultUrl(resultUrl string) string {
+	parsedUrl, err := url.Parse(strings.TrimSpace(resultUrl))
+	if err != nil || len(parsedUrl.Host) == 0 {
+		return resultUrl
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(parsedUrl.Host), "www.")
+	path := strings.TrimSuffix(parsedUrl.EscapedPath(), "/")
+
+	query := parsedUrl.Query()
+	for param := range query {
+		if strings.HasPrefix(param, "utm_") {
+			query.Del(param)
+		}
+	}
+
+	normalizedUrl := host + path
+	if len(query) > 0 {
+		normalizedUrl += "?" + query.Encode()
+	}
+
+	return normalizedUrl
+}
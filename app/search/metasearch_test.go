@@ -0,0 +1,178 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeResultUrl(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://example.com/page", want: "example.com/page"},
+		{url: "http://www.example.com/page/", want: "example.com/page"},
+		{url: "https://WWW.Example.com/page", want: "example.com/page"},
+		{url: "  https://example.com/  ", want: "example.com"},
+		{url: "https://example.com/page?utm_source=x&utm_medium=y", want: "example.com/page"},
+		{url: "https://example.com/page?b=2&a=1&utm_campaign=z", want: "example.com/page?a=1&b=2"},
+		{url: "https://example.com/Page", want: "example.com/Page"},
+		{url: "https://example.com/a%20b", want: "example.com/a%20b"},
+		{url: "/relative/path", want: "/relative/path"},
+		{url: "not a url", want: "not a url"},
+	}
+
+	for _, test := range tests {
+		if got := normalizeResultUrl(test.url); got != test.want {
+			t.Errorf("normalizeResultUrl(%q) = %q, want %q", test.url, got, test.want)
+		}
+	}
+}
+
+func backendPage(backendName string, urls []string, nextLinkPresent bool) backendSearchResult {
+	results := make([]SearchResult, len(urls))
+	for i, resultUrl := range urls {
+		results[i] = SearchResult{Url: resultUrl, Title: resultUrl}
+	}
+
+	return backendSearchResult{
+		backendName: backendName,
+		response: SearchResponse{
+			Type: SearchResponsePage,
+			SearchPage: &SearchPage{
+				SearchResults: results,
+				Pagination:    SinglePagePagination{NextLinkPresent: nextLinkPresent},
+			},
+		},
+	}
+}
+
+type mergedResult struct {
+	url     string
+	engines []string
+}
+
+func TestMergeSearchPages(t *testing.T) {
+	tests := []struct {
+		name  string
+		pages []backendSearchResult
+		want  []mergedResult
+	}{
+		{
+			name: "single backend keeps its order",
+			pages: []backendSearchResult{
+				backendPage("google", []string{"https://a.com", "https://b.com"}, false),
+			},
+			want: []mergedResult{
+				{url: "https://a.com", engines: []string{"google"}},
+				{url: "https://b.com", engines: []string{"google"}},
+			},
+		},
+		{
+			name: "results found by several backends rank first",
+			pages: []backendSearchResult{
+				backendPage("google", []string{"https://a.com", "https://b.com", "https://c.com"}, false),
+				backendPage("bing", []string{"https://c.com", "https://d.com"}, false),
+			},
+			want: []mergedResult{
+				{url: "https://c.com", engines: []string{"google", "bing"}},
+				{url: "https://a.com", engines: []string{"google"}},
+				{url: "https://b.com", engines: []string{"google"}},
+				{url: "https://d.com", engines: []string{"bing"}},
+			},
+		},
+		{
+			name: "equal scores keep the order of the backends",
+			pages: []backendSearchResult{
+				backendPage("google", []string{"https://a.com"}, false),
+				backendPage("bing", []string{"https://b.com"}, false),
+			},
+			want: []mergedResult{
+				{url: "https://a.com", engines: []string{"google"}},
+				{url: "https://b.com", engines: []string{"bing"}},
+			},
+		},
+		{
+			name: "variants of a url from the same backend count once",
+			pages: []backendSearchResult{
+				backendPage("google", []string{"https://a.com", "https://b.com"}, false),
+				backendPage("duckduckgo", []string{"https://b.com", "http://www.b.com/", "https://a.com"}, false),
+			},
+			want: []mergedResult{
+				{url: "https://b.com", engines: []string{"google", "duckduckgo"}},
+				{url: "https://a.com", engines: []string{"google", "duckduckgo"}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			page := mergeSearchPages("term", 0, test.pages)
+
+			got := make([]mergedResult, len(page.SearchResults))
+			for i, result := range page.SearchResults {
+				got[i] = mergedResult{url: result.Url, engines: result.Engines}
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMergeSearchPagesPagination(t *testing.T) {
+	tests := []struct {
+		start    int
+		nextLink bool
+		want     SinglePagePagination
+	}{
+		{
+			start:    0,
+			nextLink: true,
+			want:     SinglePagePagination{NextLinkPresent: true, NextOffset: 10},
+		},
+		{
+			start:    10,
+			nextLink: true,
+			want:     SinglePagePagination{PreviousLinkPresent: true, PreviousOffset: 0, NextLinkPresent: true, NextOffset: 20, CurrentTitle: "2"},
+		},
+		{
+			start:    30,
+			nextLink: false,
+			want:     SinglePagePagination{PreviousLinkPresent: true, PreviousOffset: 20, NextOffset: 40, CurrentTitle: "4"},
+		},
+	}
+
+	for _, test := range tests {
+		pages := []backendSearchResult{
+			backendPage("google", []string{"https://a.com"}, false),
+			backendPage("bing", []string{"https://b.com"}, test.nextLink),
+		}
+
+		if got := mergeSearchPages("term", test.start, pages).Pagination; got != test.want {
+			t.Errorf("start %d: got pagination %+v, want %+v", test.start, got, test.want)
+		}
+	}
+}
+
+func TestMetasearchBackendParams(t *testing.T) {
+	tests := []struct {
+		backend Backend
+		start   int
+		want    int
+	}{
+		{backend: &GoogleBackend{}, start: 0, want: 0},
+		{backend: &GoogleBackend{}, start: 20, want: 20},
+		{backend: &DuckDuckGoBackend{}, start: 0, want: 0},
+		{backend: &DuckDuckGoBackend{}, start: 10, want: 30},
+		{backend: &DuckDuckGoBackend{}, start: 20, want: 60},
+	}
+
+	for _, test := range tests {
+		params := metasearchBackendParams(test.backend, SearchQueryParams{Start: test.start})
+		if params.Start != test.want {
+			t.Errorf("%s at %d: got start %d, want %d", test.backend.Name(), test.start, params.Start, test.want)
+		}
+	}
+}
@@ -3,7 +3,7 @@ package search
 import (
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/url"
 	"strconv"
 	"strings"
@@ -11,6 +11,10 @@ import (
 	"github.com/PuerkitoBio/goquery"
 )
 
+// Google answers with its captcha page instead of results when it suspects
+// automated requests, sometimes with status 200
+const captchaSelector = "#captcha-form, .g-recaptcha"
+
 const (
 	PaginationTypeMultiPage  = "MultiPagePagination"  // default google search pagination
 	PaginationTypeSinglePage = "SinglePagePagination" // non-js image search pagination
@@ -20,6 +24,7 @@ type SearchResult struct {
 	Url         string
 	Title       string
 	Description string
+	Engines     []string // backends which returned the result in the metasearch mode
 }
 
 type PageLink struct {
@@ -46,15 +51,25 @@ type SearchPage struct {
 	SearchResults    []SearchResult
 	Pagination       SinglePagePagination
 	SearchCorrection SearchCorrection
+	Answers          []string // quick answers e.g. calculations or unit conversions
+	Suggestions      []string // related search terms
 }
 
+const (
+	googleWebParser    = GoogleBackendName + "/" + webParser
+	googleImagesParser = GoogleBackendName + "/" + imagesParser
+	googleVideosParser = GoogleBackendName + "/" + videosParser
+)
+
 type CaptchaPage struct {
-	SearchTerm string
+	SearchTerm   string
+	BackendTitle string
+	SearchUrl    string
 }
 
-func parsePagination(document *goquery.Document) (SinglePagePagination, error) {
-	footer := findSingle(document.Selection, "footer > div")
-	paginationDiv := findSingle(footer, "div > a").Parent()
+func parsePagination(document *goquery.Document, selectors SearchPageSelectors) (SinglePagePagination, error) {
+	footer := findFirst(document.Selection, selectors.PaginationFooter)
+	paginationDiv := findFirst(footer, selectors.PaginationLink).Parent()
 
 	if selectionEmpty(paginationDiv) {
 		return SinglePagePagination{}, errors.New("pagination not found")
@@ -68,8 +83,8 @@ func parsePagination(document *goquery.Document) (SinglePagePagination, error) {
 		CurrentTitle:        "",
 	}
 
-	links := selectionToArray(paginationDiv.Find("a"))
-	span := findSingle(paginationDiv, "div > span")
+	links := selectionToArray(findAll(paginationDiv, selectors.PaginationLinks))
+	span := findFirst(paginationDiv, selectors.PaginationCurrent)
 
 	if len(links) == 0 {
 		return pagination, errors.New("pagination links not found")
@@ -114,24 +129,30 @@ func parsePagination(document *goquery.Document) (SinglePagePagination, error) {
 	return pagination, nil
 }
 
-func parseSearchResults(document *goquery.Document) []SearchResult {
+func parseSearchResults(document *goquery.Document, selectors SearchPageSelectors) []SearchResult {
 	results := []SearchResult{}
 
-	document.Find(".fP1Qef").Each(func(i int, searchItem *goquery.Selection) {
-		titleElement := findSingle(searchItem, "h3")
+	findAll(document.Selection, selectors.Result).Each(func(i int, searchItem *goquery.Selection) {
+		titleElement := findFirst(searchItem, selectors.ResultTitle)
 		if selectionEmpty(titleElement) {
 			return
 		}
 
 		title := titleElement.Text()
-		url, _ := findSingle(searchItem, "a").Attr("href")
+		url, hasLink := findFirst(searchItem, selectors.ResultLink).Attr("href")
 		url = hrefFromQuery(url)
 
+		if !hasLink {
+			recordMissingField(googleWebParser, "result link")
+		}
+
 		description := ""
-		descriptionElement := findSingle(searchItem, ".BNeawe.s3v9rd.AP7Wnd")
+		descriptionElement := findFirst(searchItem, selectors.ResultDescription)
 
 		if !selectionEmpty(descriptionElement) {
 			description = descriptionElement.Text()
+		} else {
+			recordMissingField(googleWebParser, "result description")
 		}
 
 		results = append(results, SearchResult{
@@ -144,8 +165,8 @@ func parseSearchResults(document *goquery.Document) []SearchResult {
 	return results
 }
 
-func parseSearchCorrection(document *goquery.Document) SearchCorrection {
-	correctionContainer := findSingle(document.Selection, "#scc")
+func parseSearchCorrection(document *goquery.Document, selectors SearchPageSelectors) SearchCorrection {
+	correctionContainer := findFirst(document.Selection, selectors.Correction)
 
 	correction := SearchCorrection{
 		Present: false,
@@ -153,9 +174,9 @@ func parseSearchCorrection(document *goquery.Document) SearchCorrection {
 
 	if correctionContainer.Length() != 0 {
 		correction.Present = true
-		title := findSingle(correctionContainer, ".EE3Upf").Text()
+		title := findFirst(correctionContainer, selectors.CorrectionTitle).Text()
 		correction.Title = strings.Split(title, ":")[0]
-		correctionHref, _ := findSingle(correctionContainer, "a").Attr("href")
+		correctionHref, _ := findFirst(correctionContainer, selectors.CorrectionLink).Attr("href")
 		correctionUrl, _ := url.Parse(correctionHref)
 		correctionSearch := correctionUrl.Query().Get("q")
 		correction.CorrectSearchTerm = correctionSearch
@@ -164,20 +185,34 @@ func parseSearchCorrection(document *goquery.Document) SearchCorrection {
 	return correction
 }
 
-func parseSearchInput(document *goquery.Document) string {
-	searchInput := findSingle(document.Selection, "input[name=\"q\"]")
+func parseSearchInput(document *goquery.Document, selectors Selectors) string {
+	searchInput := findFirst(document.Selection, selectors)
 	searchText := searchInput.AttrOr("value", "")
 	return searchText
 }
 
+func isCaptchaPage(document *goquery.Document) bool {
+	return document.Find(captchaSelector).Length() > 0
+}
+
 func parseSearchPage(document *goquery.Document, start int) (*SearchPage, error) {
-	searchInput := parseSearchInput(document)
-	searchResults := parseSearchResults(document)
-	searchCorrection := parseSearchCorrection(document)
-	pagination, err := parsePagination(document)
+	if isCaptchaPage(document) {
+		return nil, ErrCaptcha
+	}
+
+	selectors := getSelectorProfile().Search
+	searchInput := parseSearchInput(document, selectors.SearchInput)
+	searchResults := parseSearchResults(document, selectors)
+	searchCorrection := parseSearchCorrection(document, selectors)
+	pagination, err := parsePagination(document, selectors)
 
 	if err != nil {
-		log.Printf("pagination error: %s", err)
+		slog.Debug("pagination error", "parser", googleWebParser, "error", err)
+		recordPaginationError(googleWebParser, err)
+	}
+
+	if len(searchInput) == 0 {
+		recordMissingField(googleWebParser, "search input")
 	}
 
 	searchPage := SearchPage{
@@ -1,6 +1,10 @@
 package search
 
-import "github.com/PuerkitoBio/goquery"
+import (
+	"bytes"
+
+	"github.com/PuerkitoBio/goquery"
+)
 
 func selectionEmpty(selection *goquery.Selection) bool {
 	return selection.Length() == 0
@@ -13,3 +17,7 @@ func findSingle(selection *goquery.Selection, selector string) *goquery.Selectio
 func hasInside(selection *goquery.Selection, selector string) bool {
 	return !selectionEmpty(findSingle(selection, selector))
 }
+
+func newDocument(body []byte) (*goquery.Document, error) {
+	return goquery.NewDocumentFromReader(bytes.NewReader(body))
+}
@@ -0,0 +1,338 @@
+package search
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"sitelook/app/metrics"
+
+	netproxy "golang.org/x/net/proxy"
+)
+
+// Ways of picking the proxy of a backend request
+const (
+	ProxyRoundRobin = "round-robin"
+	ProxyHealth     = "health"
+)
+
+// How long connecting to a proxy and opening the tunnel through it may take.
+// A proxy which doesn't answer fails before the request times out, so the
+// request is retried through the next one.
+var proxyDialTimeout = 5 * time.Second
+
+// proxies rotates backend requests through outbound proxies, requests are
+// sent directly when it's nil
+var proxies *proxyPool
+
+type proxyPool struct {
+	mutex    sync.Mutex
+	proxies  []*upstreamProxy
+	next     int
+	rotation string
+}
+
+// upstreamProxy is a proxy of the pool, it has its own connections so
+// requests picking it aren't sent over the connections of another proxy
+type upstreamProxy struct {
+	url     *url.URL
+	client  *http.Client
+	breaker *circuitBreaker
+
+	// isolate sends random socks credentials with every connection. Tor
+	// opens a separate circuit for every set of credentials.
+	isolate bool
+}
+
+// proxyError wraps errors of dialing through a proxy, so they can be told
+// apart from errors of the backend with errors.Is(err, ErrProxy)
+type proxyError struct {
+	proxy string
+	err   error
+}
+
+func (e *proxyError) Error() string {
+	return fmt.Sprintf("%s: %s", e.proxy, e.err)
+}
+
+func (e *proxyError) Unwrap() []error {
+	return []error{ErrProxy, e.err}
+}
+
+// SetProxies sends requests to backends through http, https or socks5
+// proxies. The proxy of each request is picked round-robin or, with the
+// health rotation, proxies failing repeatedly are skipped for the breaker
+// cooldown. isolate gives every request to a socks5 proxy its own Tor
+// circuit. No proxies restores direct requests.
+func SetProxies(proxyUrls []string, rotation string, isolate bool) error {
+	if rotation != ProxyRoundRobin && rotation != ProxyHealth {
+		return fmt.Errorf("unknown proxy rotation %q (available: %s, %s)", rotation, ProxyRoundRobin, ProxyHealth)
+	}
+
+	if len(proxyUrls) == 0 {
+		proxies = nil
+		return nil
+	}
+
+	pool := &proxyPool{rotation: rotation}
+
+	for _, proxyUrl := range proxyUrls {
+		parsedUrl, err := url.Parse(proxyUrl)
+		if err != nil {
+			return err
+		}
+
+		switch parsedUrl.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return fmt.Errorf("unsupported proxy scheme %q", parsedUrl.Scheme)
+		}
+
+		proxy := &upstreamProxy{
+			url:     parsedUrl,
+			breaker: &circuitBreaker{},
+			isolate: isolate && parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https",
+		}
+
+		transport := newUpstreamTransport()
+		transport.Proxy = nil
+		transport.DialContext = proxy.dialContext
+		// a reused connection would stay on the circuit it was opened on
+		transport.DisableKeepAlives = proxy.isolate
+
+		proxy.client = &http.Client{Transport: transport}
+		pool.proxies = append(pool.proxies, proxy)
+	}
+
+	proxies = pool
+	return nil
+}
+
+// pick returns the proxy of the next request. The health rotation passes
+// over proxies whose breaker is open, unless every proxy is failing.
+func (p *proxyPool) pick() *upstreamProxy {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for i := 0; i < len(p.proxies); i++ {
+		proxy := p.proxies[p.next]
+		p.next = (p.next + 1) % len(p.proxies)
+
+		if p.rotation == ProxyRoundRobin || proxy.breaker.allow() {
+			return proxy
+		}
+	}
+
+	proxy := p.proxies[p.next]
+	p.next = (p.next + 1) % len(p.proxies)
+	return proxy
+}
+
+// record updates the health of a proxy. Captchas count against it too, since
+// they mean the backend blocked the proxy's address.
+func (p *proxyPool) record(proxy *upstreamProxy, err error, status int) {
+	proxyFailed := errors.Is(err, ErrProxy)
+	if proxyFailed {
+		metrics.CountProxyFailure(proxy.url.Host)
+	}
+
+	if !proxyFailed && status != http.StatusTooManyRequests {
+		if err == nil {
+			proxy.breaker.recordSuccess()
+		}
+		return
+	}
+
+	if proxy.breaker.recordFailure(false) && p.rotation == ProxyHealth {
+		slog.Warn("proxy skipped", "proxy", proxy.url.Host, "cooldown", breakerCooldown, "status", status, "error", err)
+	}
+}
+
+// fetchThroughProxies sends the request through a proxy of the pool. When the
+// proxy fails the request is retried through the next one, until every proxy
+// was tried or the request times out.
+func fetchThroughProxies(ctx context.Context, pool *proxyPool, documentUrl string, header http.Header) (body []byte, err error, status int) {
+	for i := 0; i < len(pool.proxies); i++ {
+		proxy := pool.pick()
+		body, err, status = fetchWithClient(ctx, proxy.client, documentUrl, browserHeader(ctx, proxy.url.Host, header))
+		pool.record(proxy, err, status)
+
+		if !errors.Is(err, ErrProxy) || ctx.Err() != nil {
+			break
+		}
+
+		slog.Debug("proxy failed, retrying through the next one", "proxy", proxy.url.Host, "error", err)
+	}
+
+	return body, err, status
+}
+
+// ProxyTransport returns a transport sending requests through the proxy pool,
+// picked and retried like backend requests, or nil without proxies
+func ProxyTransport() http.RoundTripper {
+	if proxies == nil {
+		return nil
+	}
+
+	return proxies
+}
+
+// RoundTrip sends the request through a proxy of the pool, retrying through
+// the next one when the proxy fails. Only proxy failures count against its
+// health, other servers than the backends don't block proxies.
+func (p *proxyPool) RoundTrip(req *http.Request) (res *http.Response, err error) {
+	for i := 0; i < len(p.proxies); i++ {
+		proxy := p.pick()
+		res, err = proxy.client.Transport.RoundTrip(req)
+		p.record(proxy, err, 0)
+
+		if !errors.Is(err, ErrProxy) || req.Context().Err() != nil {
+			break
+		}
+	}
+
+	return res, err
+}
+
+func (p *upstreamProxy) dialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	var conn net.Conn
+	var err error
+
+	dialCtx, cancel := context.WithTimeout(ctx, proxyDialTimeout)
+	defer cancel()
+
+	if p.url.Scheme == "socks5" || p.url.Scheme == "socks5h" {
+		conn, err = p.dialSocks(dialCtx, network, address)
+	} else {
+		conn, err = p.dialConnect(dialCtx, network, address)
+	}
+
+	// cancelled requests aren't the proxy's fault
+	if err != nil && ctx.Err() == nil {
+		return nil, &proxyError{proxy: p.url.Host, err: err}
+	}
+
+	return conn, err
+}
+
+// dialSocks connects to address through a socks5 proxy, which resolves the
+// host name itself
+func (p *upstreamProxy) dialSocks(ctx context.Context, network string, address string) (net.Conn, error) {
+	var auth *netproxy.Auth
+
+	if p.isolate {
+		auth = &netproxy.Auth{User: randomCredential(), Password: randomCredential()}
+	} else if p.url.User != nil {
+		password, _ := p.url.User.Password()
+		auth = &netproxy.Auth{User: p.url.User.Username(), Password: password}
+	}
+
+	dialer, err := netproxy.SOCKS5("tcp", proxyAddress(p.url), auth, &net.Dialer{Timeout: proxyDialTimeout})
+	if err != nil {
+		return nil, err
+	}
+
+	return dialer.(netproxy.ContextDialer).DialContext(ctx, network, address)
+}
+
+// dialConnect opens a tunnel to address with an HTTP CONNECT request, the
+// connection to an https proxy is encrypted itself
+func (p *upstreamProxy) dialConnect(ctx context.Context, network string, address string) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: proxyDialTimeout}
+	proxyConn, err := dialer.DialContext(ctx, network, proxyAddress(p.url))
+	if err != nil {
+		return nil, err
+	}
+
+	// the exchange with the proxy has to finish within the dial timeout and
+	// is aborted when the request is cancelled
+	proxyConn.SetDeadline(time.Now().Add(proxyDialTimeout))
+	stop := context.AfterFunc(ctx, func() {
+		proxyConn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	conn := proxyConn
+
+	if p.url.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: p.url.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	connectReq := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: http.Header{},
+	}
+
+	if p.url.User != nil {
+		password, _ := p.url.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(p.url.User.Username() + ":" + password))
+		connectReq.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+
+	if err := connectReq.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// the client speaks first through the tunnel, so the reader can't buffer
+	// data past the response
+	res, err := http.ReadResponse(bufio.NewReader(conn), connectReq)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy responded to CONNECT with status %d", res.StatusCode)
+	}
+
+	if !stop() {
+		conn.Close()
+		return nil, ctx.Err()
+	}
+	proxyConn.SetDeadline(time.Time{})
+
+	return conn, nil
+}
+
+// proxyAddress returns the host:port of a proxy, filling in the default port
+// of its scheme
+func proxyAddress(proxyUrl *url.URL) string {
+	if len(proxyUrl.Port()) > 0 {
+		return proxyUrl.Host
+	}
+
+	port := "1080"
+	if proxyUrl.Scheme == "http" {
+		port = "80"
+	} else if proxyUrl.Scheme == "https" {
+		port = "443"
+	}
+
+	return net.JoinHostPort(proxyUrl.Hostname(), port)
+}
+
+func randomCredential() string {
+	bytes := make([]byte, 8)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
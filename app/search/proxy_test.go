@@ -0,0 +1,329 @@
+package search
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// useProxies sends backend requests of a test through the proxies and
+// restores direct requests afterwards
+func useProxies(t *testing.T, proxyUrls []string, rotation string, isolate bool) {
+	t.Helper()
+
+	if err := SetProxies(proxyUrls, rotation, isolate); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { proxies = nil })
+}
+
+// newBackendStandIn answers every request with the same page
+func newBackendStandIn(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "results")
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// connectProxy is an http proxy tunnelling CONNECT requests, or answering
+// them with status when it is set
+type connectProxy struct {
+	server *httptest.Server
+	status int
+
+	mutex          sync.Mutex
+	authorizations []string
+}
+
+func newConnectProxy(t *testing.T, status int) *connectProxy {
+	t.Helper()
+
+	proxy := &connectProxy{status: status}
+	proxy.server = httptest.NewServer(http.HandlerFunc(proxy.serveConnect))
+	t.Cleanup(proxy.server.Close)
+
+	return proxy
+}
+
+func (p *connectProxy) serveConnect(w http.ResponseWriter, r *http.Request) {
+	p.mutex.Lock()
+	p.authorizations = append(p.authorizations, r.Header.Get("Proxy-Authorization"))
+	p.mutex.Unlock()
+
+	if r.Method != http.MethodConnect {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if p.status != 0 {
+		w.WriteHeader(p.status)
+		return
+	}
+
+	backendConn, err := net.Dial("tcp", r.Host)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	defer backendConn.Close()
+
+	clientConn, _, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		return
+	}
+	defer clientConn.Close()
+
+	io.WriteString(clientConn, "HTTP/1.1 200 Connection established\r\n\r\n")
+
+	go io.Copy(backendConn, clientConn)
+	io.Copy(clientConn, backendConn)
+}
+
+func (p *connectProxy) url(credentials string) string {
+	return strings.Replace(p.server.URL, "http://", "http://"+credentials, 1)
+}
+
+func (p *connectProxy) receivedAuthorizations() []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return append([]string{}, p.authorizations...)
+}
+
+// newListener accepts connections and passes them to handle, it counts the
+// accepted connections
+func newListener(t *testing.T, handle func(conn net.Conn)) (address string, accepted func() int) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	var mutex sync.Mutex
+	count := 0
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			mutex.Lock()
+			count++
+			mutex.Unlock()
+
+			go handle(conn)
+		}
+	}()
+
+	return listener.Addr().String(), func() int {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return count
+	}
+}
+
+// closedAddress returns an address nothing listens on
+func closedAddress(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	address := listener.Addr().String()
+	listener.Close()
+	return address
+}
+
+func TestFetchThroughConnectProxy(t *testing.T) {
+	backend := newBackendStandIn(t)
+	proxy := newConnectProxy(t, 0)
+	useProxies(t, []string{proxy.url("user:secret@")}, ProxyRoundRobin, false)
+
+	body, err, status := fetchThroughProxies(context.Background(), proxies, backend.URL, http.Header{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(body) != "results" || status != http.StatusOK {
+		t.Errorf("got %q with status %d, want the backend's page", body, status)
+	}
+
+	// base64 of user:secret
+	if got, want := proxy.receivedAuthorizations(), []string{"Basic dXNlcjpzZWNyZXQ="}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got authorizations %v, want %v", got, want)
+	}
+}
+
+func TestFetchThroughConnectProxyRequiringAuthentication(t *testing.T) {
+	backend := newBackendStandIn(t)
+	proxy := newConnectProxy(t, http.StatusProxyAuthRequired)
+	useProxies(t, []string{proxy.url("")}, ProxyRoundRobin, false)
+
+	_, err, _ := fetchThroughProxies(context.Background(), proxies, backend.URL, http.Header{})
+	if !errors.Is(err, ErrProxy) {
+		t.Fatalf("got error %v, want a proxy error", err)
+	}
+
+	if !strings.Contains(err.Error(), "407") {
+		t.Errorf("error %q doesn't mention the status", err)
+	}
+}
+
+func TestFetchThroughRefusedSocksProxy(t *testing.T) {
+	backend := newBackendStandIn(t)
+	useProxies(t, []string{"socks5://" + closedAddress(t)}, ProxyRoundRobin, false)
+
+	_, err, _ := fetchThroughProxies(context.Background(), proxies, backend.URL, http.Header{})
+	if !errors.Is(err, ErrProxy) {
+		t.Fatalf("got error %v, want a proxy error", err)
+	}
+
+	if responseType, _ := fetchError(&GoogleBackend{}, backend.URL, err); responseType != SearchResponseProxyError {
+		t.Errorf("got response type %d, want %d", responseType, SearchResponseProxyError)
+	}
+}
+
+func TestFetchThroughUnresponsiveProxy(t *testing.T) {
+	previousTimeout := proxyDialTimeout
+	proxyDialTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { proxyDialTimeout = previousTimeout })
+
+	// accepts connections but never answers the CONNECT request
+	address, _ := newListener(t, func(conn net.Conn) {
+		io.Copy(io.Discard, conn)
+	})
+
+	backend := newBackendStandIn(t)
+	useProxies(t, []string{"http://" + address}, ProxyRoundRobin, false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	started := time.Now()
+	_, err, _ := fetchThroughProxies(ctx, proxies, backend.URL, http.Header{})
+
+	if !errors.Is(err, ErrProxy) {
+		t.Fatalf("got error %v, want a proxy error", err)
+	}
+
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("proxy failed after %s, want it to fail on the dial timeout", elapsed)
+	}
+}
+
+func TestHealthRotationSkipsFailedProxy(t *testing.T) {
+	resetBreakers(t, 1, time.Minute)
+
+	// closes every connection before answering
+	failingAddress, failingConnections := newListener(t, func(conn net.Conn) {
+		conn.Close()
+	})
+
+	backend := newBackendStandIn(t)
+	proxy := newConnectProxy(t, 0)
+	useProxies(t, []string{"http://" + failingAddress, proxy.url("")}, ProxyHealth, false)
+
+	for i := 0; i < 3; i++ {
+		body, err, _ := fetchThroughProxies(context.Background(), proxies, backend.URL, http.Header{})
+		if err != nil || string(body) != "results" {
+			t.Fatalf("search %d got %q, error %v, want the backend's page through the other proxy", i+1, body, err)
+		}
+	}
+
+	if got := failingConnections(); got != 1 {
+		t.Errorf("failing proxy got %d connections, want 1 before it is skipped", got)
+	}
+}
+
+// serveSocksCredentials answers the socks5 handshake with username and
+// password authentication, records the username and refuses the connection
+// request
+func serveSocksCredentials(conn net.Conn, usernames chan<- string) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	// version, methods
+	greeting := make([]byte, 2)
+	if _, err := io.ReadFull(reader, greeting); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(reader, make([]byte, greeting[1])); err != nil {
+		return
+	}
+	conn.Write([]byte{0x05, 0x02})
+
+	// version, username, password
+	version, _ := reader.ReadByte()
+	length, _ := reader.ReadByte()
+	username := make([]byte, length)
+	if _, err := io.ReadFull(reader, username); err != nil || version != 0x01 {
+		return
+	}
+	length, _ = reader.ReadByte()
+	if _, err := io.ReadFull(reader, make([]byte, length)); err != nil {
+		return
+	}
+	conn.Write([]byte{0x01, 0x00})
+
+	usernames <- string(username)
+
+	// general failure for the connection request
+	conn.Write([]byte{0x05, 0x01, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+}
+
+func TestProxyIsolationSendsNewCredentials(t *testing.T) {
+	usernames := make(chan string, 2)
+	address, _ := newListener(t, func(conn net.Conn) {
+		serveSocksCredentials(conn, usernames)
+	})
+
+	useProxies(t, []string{"socks5://user:secret@" + address}, ProxyRoundRobin, true)
+
+	for i := 0; i < 2; i++ {
+		if _, err, _ := fetchThroughProxies(context.Background(), proxies, "http://backend.example/search", http.Header{}); !errors.Is(err, ErrProxy) {
+			t.Fatalf("got error %v, want the refused request", err)
+		}
+	}
+
+	first, second := <-usernames, <-usernames
+	if first == "user" || second == "user" || first == second {
+		t.Errorf("got usernames %q and %q, want a new random one for every connection", first, second)
+	}
+}
+
+func TestProxyTransportRetriesNextProxy(t *testing.T) {
+	backend := newBackendStandIn(t)
+	proxy := newConnectProxy(t, 0)
+	useProxies(t, []string{"http://" + closedAddress(t), proxy.url("")}, ProxyRoundRobin, false)
+
+	client := http.Client{Transport: ProxyTransport()}
+
+	res, err := client.Get(backend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if body, _ := io.ReadAll(res.Body); string(body) != "results" {
+		t.Errorf("got %q, want the backend's page through the other proxy", body)
+	}
+}
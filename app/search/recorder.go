@@ -0,0 +1,95 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var ErrNotRecorded = errors.New("upstream response was not recorded")
+
+// Every upstream response is written to recordDir when it is set. When
+// replayDir is set responses are read from it instead of the network, which
+// allows reproducing parser failures captured on a running instance.
+var recordDir = ""
+var replayDir = ""
+
+func SetRecordDir(dir string) error {
+	if len(dir) > 0 {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	recordDir = dir
+	return nil
+}
+
+func SetReplayDir(dir string) error {
+	if len(dir) > 0 {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			return fmt.Errorf("replay path %q is not a directory", dir)
+		}
+	}
+
+	replayDir = dir
+	return nil
+}
+
+// Recordings are prefixed with the host to make pages of a backend easy to
+// find, the url itself is stored in the metadata file
+func recordingName(responseUrl string) string {
+	name := diskCacheName(responseUrl)
+
+	parsedUrl, err := url.Parse(responseUrl)
+	if err != nil || len(parsedUrl.Host) == 0 {
+		return name
+	}
+
+	return strings.ReplaceAll(parsedUrl.Host, ":", "_") + "-" + name
+}
+
+func recordResponse(responseUrl string, body []byte, status int) {
+	meta := responseMeta{
+		Url:       responseUrl,
+		Status:    status,
+		Size:      int64(len(body)),
+		FetchedAt: time.Now(),
+	}
+
+	path := filepath.Join(recordDir, recordingName(responseUrl))
+	if err := writeResponse(path, meta, body); err != nil {
+		slog.Error("recording failed", "error", err)
+	}
+}
+
+func replayResponse(responseUrl string) (body []byte, err error, status int) {
+	name := recordingName(responseUrl)
+	path := filepath.Join(replayDir, name)
+
+	// the recording is named by its file, the url would put the search term
+	// into the logs
+	meta, err := readResponseMeta(path + responseMetaExtension)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotRecorded, name+responseMetaExtension), 0
+	} else if err != nil {
+		return nil, err, 0
+	}
+
+	body, err = os.ReadFile(path + responseBodyExtension)
+	if err != nil {
+		return nil, err, 0
+	}
+
+	return body, nil, meta.Status
+}
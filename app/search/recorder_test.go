@@ -0,0 +1,113 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// useRecordings sets the record and replay directories of a test and turns
+// both modes off afterwards
+func useRecordings(t *testing.T, record string, replay string) {
+	t.Helper()
+
+	if err := SetRecordDir(record); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetReplayDir(replay); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		SetRecordDir("")
+		SetReplayDir("")
+	})
+}
+
+// newUpstreamStandIn answers every request with the body and status and
+// counts the requests
+func newUpstreamStandIn(t *testing.T, body string, status int) (server *httptest.Server, requests *atomic.Int32) {
+	t.Helper()
+
+	requests = &atomic.Int32{}
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, requests
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	server, requests := newUpstreamStandIn(t, "no results", http.StatusNotFound)
+	searchUrl := server.URL + "/search?q=golang"
+
+	useRecordings(t, dir, "")
+
+	body, err, status := getDocument(context.Background(), "stand-in", searchUrl, nil)
+	if err != nil || string(body) != "no results" || status != http.StatusNotFound {
+		t.Fatalf("got %q with status %d, error %v, want the upstream response", body, status, err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := strings.ReplaceAll(strings.TrimPrefix(server.URL, "http://"), ":", "_")
+	if len(files) != 2 || !strings.HasPrefix(filepath.Base(files[0]), host+"-") {
+		t.Errorf("got recordings %v, want a body and metadata file named by the host", files)
+	}
+
+	useRecordings(t, "", dir)
+
+	body, err, status = getDocument(context.Background(), "stand-in", searchUrl, nil)
+	if err != nil || string(body) != "no results" || status != http.StatusNotFound {
+		t.Errorf("got %q with status %d, error %v, want the recorded response", body, status, err)
+	}
+
+	if got := requests.Load(); got != 1 {
+		t.Errorf("upstream got %d requests, want 1 while recording", got)
+	}
+}
+
+func TestReplayWithoutRecording(t *testing.T) {
+	server, requests := newUpstreamStandIn(t, "results", http.StatusOK)
+	useRecordings(t, "", t.TempDir())
+
+	_, err, _ := getDocument(context.Background(), "stand-in", server.URL+"/search?q=secret+term", nil)
+	if !errors.Is(err, ErrNotRecorded) {
+		t.Fatalf("got error %v, want %v", err, ErrNotRecorded)
+	}
+
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("error %q contains the search term", err)
+	}
+
+	if got := requests.Load(); got != 0 {
+		t.Errorf("upstream got %d requests in the replay mode", got)
+	}
+}
+
+func TestSetReplayDirErrors(t *testing.T) {
+	t.Cleanup(func() { SetReplayDir("") })
+
+	file := filepath.Join(t.TempDir(), "recording.json")
+	if err := os.WriteFile(file, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dir := range []string{filepath.Join(t.TempDir(), "missing"), file} {
+		if err := SetReplayDir(dir); err == nil {
+			t.Errorf("replay directory %s was accepted", dir)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const SearxngBackendName = "searxng"
+
+// Number of results SearXNG returns per page, used to map `start` offsets
+// onto its `pageno` parameter
+const searxngPageSize = 10
+
+// SearxngBackend queries a SearXNG instance through its json api. The json
+// format has to be enabled in the instance's `search.formats` setting.
+type SearxngBackend struct {
+	instanceUrl *url.URL
+}
+
+func NewSearxngBackend(instanceUrl string) (*SearxngBackend, error) {
+	parsedUrl, err := url.Parse(instanceUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	if parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https" {
+		return nil, fmt.Errorf("searxng instance url %q must be an http(s) url", instanceUrl)
+	}
+
+	return &SearxngBackend{instanceUrl: parsedUrl}, nil
+}
+
+func (b *SearxngBackend) Name() string {
+	return SearxngBackendName
+}
+
+func (b *SearxngBackend) Title() string {
+	return "SearXNG"
+}
+
+func (b *SearxngBackend) SearchUrl(searchTerm string, searchType string, params SearchQueryParams) (string, error) {
+	category := ""
+
+	if len(searchType) == 0 {
+		category = "general"
+	} else if searchType == "isch" {
+		category = "images"
+	} else if searchType == "vid" {
+		category = "videos"
+	} else {
+		return "", ErrSearchTypeNotSupported
+	}
+
+	searchUrl := b.instanceUrl.JoinPath("search")
+	query := url.Values{}
+
+	query.Add("q", searchTerm)
+	query.Add("format", "json")
+	query.Add("categories", category)
+
+	if params.Start > 0 {
+		query.Add("pageno", strconv.Itoa(params.Start/searxngPageSize+1))
+	}
+
+	if language := getSearxngLanguage(params); len(language) > 0 {
+		query.Add("language", language)
+	}
+
+	searchUrl.RawQuery = query.Encode()
+	return searchUrl.String(), nil
+}
+
+func (b *SearxngBackend) Fetch(ctx context.Context, searchUrl string) (body []byte, err error, status int) {
+	header := http.Header{"Accept": {"application/json"}}
+	return getDocument(ctx, b.Name(), searchUrl, header)
+}
+
+func (b *SearxngBackend) ParseSearchPage(body []byte, params SearchQueryParams) (*SearchPage, error) {
+	response, err := parseSearxngResponse(body)
+	if err != nil {
+		return nil, err
+	}
+
+	searchPage := createSearxngSearchPage(response, params.Start)
+	return &searchPage, nil
+}
+
+func (b *SearxngBackend) ParseImagesPage(body []byte, params SearchQueryParams) (ImagesPage, error) {
+	response, err := parseSearxngResponse(body)
+	if err != nil {
+		return ImagesPage{}, err
+	}
+
+	// a response without results is a search which found nothing, the json
+	// api can't be misparsed like a changed html page
+	return createSearxngImagesPage(response, params.Start), nil
+}
+
+func (b *SearxngBackend) ParseVideosPage(body []byte, params SearchQueryParams) (VideosPage, error) {
+	response, err := parseSearxngResponse(body)
+	if err != nil {
+		return VideosPage{}, err
+	}
+
+	return createSearxngVideosPage(response, params.Start), nil
+}
+
+// SearXNG expects language codes like `en` or `en-US` instead of
+// Google's `lang_en`
+func getSearxngLanguage(params SearchQueryParams) string {
+	if len(params.SearchLanguage) > 0 {
+		return strings.TrimPrefix(params.SearchLanguage, "lang_")
+	}
+
+	return params.InterfaceLanguage
+}
+
+// The autocompleter responds in the OpenSearch suggestions format, it has
+// to be enabled in the instance's `search.autocomplete` setting
+func (b *SearxngBackend) SuggestionsUrl(searchTerm string, params SearchQueryParams) string {
+	suggestionsUrl := b.instanceUrl.JoinPath("autocompleter")
+	query := url.Values{}
+
+	query.Add("q", searchTerm)
+
+	if language := getSearxngLanguage(params); len(language) > 0 {
+		query.Add("language", language)
+	}
+
+	suggestionsUrl.RawQuery = query.Encode()
+	return suggestionsUrl.String()
+}
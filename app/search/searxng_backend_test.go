@@ -0,0 +1,193 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// newSearxngStandIn serves recorded json responses by the requested category,
+// searches for noResultsTerm find nothing
+const noResultsTerm = "qwxzvbnmlkjhgf"
+
+func newSearxngStandIn(t *testing.T) *SearxngBackend {
+	t.Helper()
+
+	fixtures := map[string]string{
+		"general": "search.json",
+		"images":  "images.json",
+		"videos":  "videos.json",
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fixture, ok := fixtures[r.URL.Query().Get("categories")]
+		if r.URL.Path != "/searxng/search" || r.URL.Query().Get("format") != "json" || !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if r.URL.Query().Get("q") == noResultsTerm {
+			fixture = "no-results.json"
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		http.ServeFile(w, r, filepath.Join("testdata", "searxng", fixture))
+	}))
+	t.Cleanup(server.Close)
+
+	backend, err := NewSearxngBackend(server.URL + "/searxng/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return backend
+}
+
+func TestSearxngSearch(t *testing.T) {
+	response, err := searchBackend(context.Background(), newSearxngStandIn(t), "golnag", SearchQueryParams{Start: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Type != SearchResponsePage {
+		t.Fatalf("got response type %d, want %d", response.Type, SearchResponsePage)
+	}
+
+	want := SearchPage{
+		SearchTerm: "golnag",
+		SearchResults: []SearchResult{
+			{
+				Url:         "https://go.dev/",
+				Title:       "The Go Programming Language",
+				Description: "Go is an open source programming language that makes it simple to build secure, scalable systems.",
+				Engines:     []string{"google", "duckduckgo"},
+			},
+			{
+				Url:         "https://en.wikipedia.org/wiki/Go_(programming_language)",
+				Title:       "Go (programming language) - Wikipedia",
+				Description: "Go is a statically typed, compiled high-level programming language designed at Google.",
+				Engines:     []string{"wikipedia"},
+			},
+		},
+		Pagination: SinglePagePagination{
+			PreviousLinkPresent: true,
+			PreviousOffset:      0,
+			NextLinkPresent:     true,
+			NextOffset:          20,
+			CurrentTitle:        "2",
+		},
+		SearchCorrection: SearchCorrection{
+			Present:           true,
+			Title:             "Did you mean",
+			CorrectSearchTerm: "golang",
+		},
+		Answers:     []string{"Go was announced in November 2009", "Go 1.0 was released in March 2012"},
+		Suggestions: []string{"golang tutorial", "golang vs rust"},
+	}
+
+	if !reflect.DeepEqual(*response.SearchPage, want) {
+		t.Errorf("got %+v, want %+v", *response.SearchPage, want)
+	}
+}
+
+func TestSearxngImageSearch(t *testing.T) {
+	response, err := imageSearchBackend(context.Background(), newSearxngStandIn(t), "gopher", SearchQueryParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := ImagesPage{
+		SearchTerm: "gopher",
+		ImageResults: []ImageResult{
+			{
+				Title:         "The Go Gopher",
+				UrlTitle:      "go.dev",
+				ImageSrc:      "https://tse1.mm.bing.net/th?id=OIP.gopher1",
+				TitleLinkHref: "https://go.dev/blog/gopher",
+				ImageLinkHref: "https://go.dev/blog/gopher/header.jpg",
+			},
+			{
+				Title:         "Gophers | National Geographic",
+				UrlTitle:      "nationalgeographic.com",
+				ImageSrc:      "https://i.natgeofe.com/n/gopher.jpg",
+				TitleLinkHref: "https://www.nationalgeographic.com/animals/mammals/facts/gophers",
+				ImageLinkHref: "https://i.natgeofe.com/n/gopher.jpg",
+			},
+		},
+		Pagination: SinglePagePagination{
+			NextLinkPresent: true,
+			NextOffset:      10,
+		},
+	}
+
+	if !reflect.DeepEqual(*response.ImagesPage, want) {
+		t.Errorf("got %+v, want %+v", *response.ImagesPage, want)
+	}
+}
+
+func TestSearxngVideoSearch(t *testing.T) {
+	response, err := videoSearchBackend(context.Background(), newSearxngStandIn(t), "golang tutorial", SearchQueryParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := VideosPage{
+		SearchTerm: "golang tutorial",
+		VideoResults: []VideoResult{
+			{
+				Title:         "Go Programming – Golang Course with Bonus Projects",
+				UrlTitle:      "https://www.youtube.com/watch?v=un6ZyFkqFKo",
+				ImageSrc:      "https://i.ytimg.com/vi/un6ZyFkqFKo/hqdefault.jpg",
+				TitleLinkHref: "https://www.youtube.com/watch?v=un6ZyFkqFKo",
+				Description:   "freeCodeCamp.org · Learn the Go programming language in this tutorial course for beginners.",
+			},
+		},
+		Pagination: SinglePagePagination{
+			NextLinkPresent: true,
+			NextOffset:      10,
+		},
+	}
+
+	if !reflect.DeepEqual(*response.VideosPage, want) {
+		t.Errorf("got %+v, want %+v", *response.VideosPage, want)
+	}
+}
+
+func TestSearxngSearchWithoutResults(t *testing.T) {
+	backend := newSearxngStandIn(t)
+
+	response, err := searchBackend(context.Background(), backend, noResultsTerm, SearchQueryParams{})
+	if err != nil || response.Type != SearchResponsePage || len(response.SearchPage.SearchResults) != 0 {
+		t.Errorf("got search response type %d, error %v, want an empty page", response.Type, err)
+	}
+
+	imagesResponse, err := imageSearchBackend(context.Background(), backend, noResultsTerm, SearchQueryParams{})
+	if err != nil || imagesResponse.Type != SearchResponsePage || len(imagesResponse.ImagesPage.ImageResults) != 0 {
+		t.Errorf("got images response type %d, error %v, want an empty page", imagesResponse.Type, err)
+	}
+
+	videosResponse, err := videoSearchBackend(context.Background(), backend, noResultsTerm, SearchQueryParams{})
+	if err != nil || videosResponse.Type != SearchResponsePage || len(videosResponse.VideosPage.VideoResults) != 0 {
+		t.Errorf("got videos response type %d, error %v, want an empty page", videosResponse.Type, err)
+	}
+}
+
+func TestSearxngSearchUrl(t *testing.T) {
+	backend, err := NewSearxngBackend("https://searx.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := backend.SearchUrl("golang", "isch", SearchQueryParams{Start: 20, SearchLanguage: "lang_de"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "https://searx.example.com/search?categories=images&format=json&language=de&pageno=3&q=golang"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
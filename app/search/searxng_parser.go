@@ -0,0 +1,175 @@
+package search
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type searxngResult struct {
+	Url          string   `json:"url"`
+	Title        string   `json:"title"`
+	Content      string   `json:"content"`
+	Engines      []string `json:"engines"`
+	ImageSrc     string   `json:"img_src"`
+	ThumbnailSrc string   `json:"thumbnail_src"`
+	Thumbnail    string   `json:"thumbnail"`
+	Author       string   `json:"author"`
+}
+
+type searxngResponse struct {
+	Query       string            `json:"query"`
+	Results     []searxngResult   `json:"results"`
+	Answers     []json.RawMessage `json:"answers"`
+	Corrections []string          `json:"corrections"`
+	Suggestions []string          `json:"suggestions"`
+}
+
+func parseSearxngResponse(body []byte) (searxngResponse, error) {
+	response := searxngResponse{}
+	err := json.Unmarshal(body, &response)
+	return response, err
+}
+
+// Older SearXNG versions return answers as strings, newer ones as objects
+// with an `answer` field
+func parseSearxngAnswer(rawAnswer json.RawMessage) string {
+	answer := ""
+	if err := json.Unmarshal(rawAnswer, &answer); err == nil {
+		return answer
+	}
+
+	answerObject := struct {
+		Answer string `json:"answer"`
+	}{}
+	if err := json.Unmarshal(rawAnswer, &answerObject); err == nil {
+		return answerObject.Answer
+	}
+
+	return ""
+}
+
+// SearXNG pages are numbered, the pagination is derived from the requested
+// offset. The next page link is shown as long as the page has results.
+func createSearxngPagination(start int, resultCount int) SinglePagePagination {
+	pagination := SinglePagePagination{
+		PreviousLinkPresent: start > 0,
+		PreviousOffset:      0,
+		NextLinkPresent:     resultCount > 0,
+		NextOffset:          start + searxngPageSize,
+		CurrentTitle:        "",
+	}
+
+	if start > searxngPageSize {
+		pagination.PreviousOffset = start - searxngPageSize
+	}
+
+	if start > 0 {
+		pagination.CurrentTitle = strconv.Itoa(start/searxngPageSize + 1)
+	}
+
+	return pagination
+}
+
+func createSearxngSearchCorrection(response searxngResponse) SearchCorrection {
+	if len(response.Corrections) == 0 {
+		return SearchCorrection{Present: false}
+	}
+
+	return SearchCorrection{
+		Present:           true,
+		Title:             "Did you mean",
+		CorrectSearchTerm: response.Corrections[0],
+	}
+}
+
+func createSearxngSearchPage(response searxngResponse, start int) SearchPage {
+	searchResults := make([]SearchResult, 0, len(response.Results))
+
+	for _, result := range response.Results {
+		searchResults = append(searchResults, SearchResult{
+			Url:         result.Url,
+			Title:       result.Title,
+			Description: strings.TrimSpace(result.Content),
+			Engines:     result.Engines,
+		})
+	}
+
+	answers := []string{}
+	for _, rawAnswer := range response.Answers {
+		if answer := parseSearxngAnswer(rawAnswer); len(answer) > 0 {
+			answers = append(answers, answer)
+		}
+	}
+
+	return SearchPage{
+		SearchTerm:       response.Query,
+		SearchResults:    searchResults,
+		Pagination:       createSearxngPagination(start, len(searchResults)),
+		SearchCorrection: createSearxngSearchCorrection(response),
+		Answers:          answers,
+		Suggestions:      response.Suggestions,
+	}
+}
+
+func createSearxngImagesPage(response searxngResponse, start int) ImagesPage {
+	imageResults := make([]ImageResult, 0, len(response.Results))
+
+	for _, result := range response.Results {
+		if len(result.ImageSrc) == 0 {
+			continue
+		}
+
+		thumbnailSrc := result.ThumbnailSrc
+		if len(thumbnailSrc) == 0 {
+			thumbnailSrc = result.ImageSrc
+		}
+
+		urlTitle := result.Url
+		if pageUrl, err := url.Parse(result.Url); err == nil {
+			urlTitle = strings.TrimPrefix(pageUrl.Host, "www.")
+		}
+
+		imageResults = append(imageResults, ImageResult{
+			Title:         result.Title,
+			UrlTitle:      urlTitle,
+			ImageSrc:      thumbnailSrc,
+			TitleLinkHref: result.Url,
+			ImageLinkHref: result.ImageSrc,
+		})
+	}
+
+	return ImagesPage{
+		SearchTerm:   response.Query,
+		ImageResults: imageResults,
+		Pagination:   createSearxngPagination(start, len(imageResults)),
+	}
+}
+
+func createSearxngVideosPage(response searxngResponse, start int) VideosPage {
+	videoResults := make([]VideoResult, 0, len(response.Results))
+
+	for _, result := range response.Results {
+		descriptionParts := []string{}
+		for _, part := range []string{result.Author, strings.TrimSpace(result.Content)} {
+			if len(part) > 0 {
+				descriptionParts = append(descriptionParts, part)
+			}
+		}
+
+		videoResults = append(videoResults, VideoResult{
+			Title:         result.Title,
+			UrlTitle:      result.Url,
+			ImageSrc:      result.Thumbnail,
+			TitleLinkHref: result.Url,
+			Description:   strings.Join(descriptionParts, " · "),
+		})
+	}
+
+	return VideosPage{
+		SearchTerm:   response.Query,
+		VideoResults: videoResults,
+		Pagination:   createSearxngPagination(start, len(videoResults)),
+	}
+}
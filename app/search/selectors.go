@@ -0,0 +1,209 @@
+package search
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"os"
+	"reflect"
+	"sync/atomic"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"gopkg.in/yaml.v3"
+)
+
+const selectorProfileVersion = 1
+
+//go:embed google_selectors.yaml
+var defaultSelectorProfile []byte
+
+// Selectors are tried in order until one of them matches. In a profile they
+// are written as a single selector or a list.
+type Selectors []string
+
+func (s *Selectors) UnmarshalYAML(value *yaml.Node) error {
+	selectors := []string{}
+
+	if value.Kind == yaml.ScalarNode {
+		selectors = append(selectors, value.Value)
+	} else if err := value.Decode(&selectors); err != nil {
+		return err
+	}
+
+	if len(selectors) == 0 {
+		return fmt.Errorf("line %d: at least one selector expected", value.Line)
+	}
+
+	for _, selector := range selectors {
+		if _, err := cascadia.Compile(selector); err != nil {
+			return fmt.Errorf("line %d: invalid selector %q: %w", value.Line, selector, err)
+		}
+	}
+
+	*s = selectors
+	return nil
+}
+
+type SearchPageSelectors struct {
+	SearchInput       Selectors `yaml:"searchInput"`
+	Result            Selectors `yaml:"result"`
+	ResultTitle       Selectors `yaml:"resultTitle"`
+	ResultLink        Selectors `yaml:"resultLink"`
+	ResultDescription Selectors `yaml:"resultDescription"`
+	Correction        Selectors `yaml:"correction"`
+	CorrectionTitle   Selectors `yaml:"correctionTitle"`
+	CorrectionLink    Selectors `yaml:"correctionLink"`
+	PaginationFooter  Selectors `yaml:"paginationFooter"`
+	PaginationLink    Selectors `yaml:"paginationLink"`
+	PaginationLinks   Selectors `yaml:"paginationLinks"`
+	PaginationCurrent Selectors `yaml:"paginationCurrent"`
+}
+
+type ImagesPageSelectors struct {
+	SearchInput     Selectors `yaml:"searchInput"`
+	Result          Selectors `yaml:"result"`
+	ResultImage     Selectors `yaml:"resultImage"`
+	ResultLinks     Selectors `yaml:"resultLinks"`
+	ResultTitles    Selectors `yaml:"resultTitles"`
+	Pagination      Selectors `yaml:"pagination"`
+	PaginationCells Selectors `yaml:"paginationCells"`
+	PaginationLink  Selectors `yaml:"paginationLink"`
+}
+
+type VideosPageSelectors struct {
+	SearchInput       Selectors `yaml:"searchInput"`
+	Result            Selectors `yaml:"result"`
+	ResultTitle       Selectors `yaml:"resultTitle"`
+	ResultLink        Selectors `yaml:"resultLink"`
+	ResultImage       Selectors `yaml:"resultImage"`
+	ResultDescription Selectors `yaml:"resultDescription"`
+	PaginationFooter  Selectors `yaml:"paginationFooter"`
+	PaginationLink    Selectors `yaml:"paginationLink"`
+	PaginationLinks   Selectors `yaml:"paginationLinks"`
+	PaginationCurrent Selectors `yaml:"paginationCurrent"`
+}
+
+// SelectorProfile holds the selectors of the Google parsers, so they can be
+// updated without a rebuild when Google changes its markup
+type SelectorProfile struct {
+	Version int                 `yaml:"version"`
+	Search  SearchPageSelectors `yaml:"search"`
+	Images  ImagesPageSelectors `yaml:"images"`
+	Videos  VideosPageSelectors `yaml:"videos"`
+}
+
+var builtinSelectorProfile *SelectorProfile
+var selectorProfile atomic.Pointer[SelectorProfile]
+
+func init() {
+	profile, err := parseSelectorProfile(defaultSelectorProfile, &SelectorProfile{})
+	if err != nil {
+		panic(fmt.Sprintf("default selector profile: %s", err))
+	}
+
+	builtinSelectorProfile = profile
+	selectorProfile.Store(profile)
+}
+
+// parseSelectorProfile decodes a yaml or json profile on top of the base
+// profile, so a profile only has to contain the selectors it changes
+func parseSelectorProfile(data []byte, base *SelectorProfile) (*SelectorProfile, error) {
+	profile := *base
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(&profile); err != nil {
+		return nil, err
+	}
+
+	if profile.Version != selectorProfileVersion {
+		return nil, fmt.Errorf("unsupported selector profile version %d (%d expected)", profile.Version, selectorProfileVersion)
+	}
+
+	if err := checkSelectors(&profile); err != nil {
+		return nil, err
+	}
+
+	return &profile, nil
+}
+
+// checkSelectors makes sure every field of the profile has a selector. An
+// empty field (e.g. `resultTitle:`) is decoded as null without a call to
+// Selectors.UnmarshalYAML and would leave the parsers without selectors.
+func checkSelectors(profile *SelectorProfile) error {
+	sections := reflect.ValueOf(*profile)
+
+	for i := 0; i < sections.NumField(); i++ {
+		section := sections.Field(i)
+		if section.Kind() != reflect.Struct {
+			continue
+		}
+
+		for j := 0; j < section.NumField(); j++ {
+			selectors, ok := section.Field(j).Interface().(Selectors)
+			if !ok {
+				continue
+			}
+
+			if len(selectors) == 0 {
+				name := sections.Type().Field(i).Tag.Get("yaml") + "." + section.Type().Field(j).Tag.Get("yaml")
+				return fmt.Errorf("%s: at least one selector expected", name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// LoadSelectorProfile replaces the selectors of the Google parsers with the
+// ones from the profile file. The current profile is kept when it is invalid.
+func LoadSelectorProfile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	profile, err := parseSelectorProfile(data, builtinSelectorProfile)
+	if err != nil {
+		return fmt.Errorf("selector profile %s: %w", path, err)
+	}
+
+	selectorProfile.Store(profile)
+	return nil
+}
+
+func getSelectorProfile() *SelectorProfile {
+	return selectorProfile.Load()
+}
+
+// findFirst returns the first element matched by the first matching selector
+func findFirst(selection *goquery.Selection, selectors Selectors) *goquery.Selection {
+	if len(selectors) == 0 {
+		return selection.Slice(0, 0)
+	}
+
+	found := findSingle(selection, selectors[0])
+
+	for i := 1; i < len(selectors) && selectionEmpty(found); i++ {
+		found = findSingle(selection, selectors[i])
+	}
+
+	return found
+}
+
+// findAll returns the elements matched by the first matching selector
+func findAll(selection *goquery.Selection, selectors Selectors) *goquery.Selection {
+	if len(selectors) == 0 {
+		return selection.Slice(0, 0)
+	}
+
+	found := selection.Find(selectors[0])
+
+	for i := 1; i < len(selectors) && selectionEmpty(found); i++ {
+		found = selection.Find(selectors[i])
+	}
+
+	return found
+}
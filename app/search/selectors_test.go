@@ -0,0 +1,152 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// useSelectorProfile restores the built-in selector profile after a test
+func useSelectorProfile(t *testing.T, profile *SelectorProfile) {
+	t.Helper()
+
+	previous := selectorProfile.Load()
+	selectorProfile.Store(profile)
+	t.Cleanup(func() { selectorProfile.Store(previous) })
+}
+
+func TestParseSelectorProfile(t *testing.T) {
+	profile, err := parseSelectorProfile([]byte("version: 1\nsearch:\n  resultTitle: h4\n  resultLink: [a.title, a]\n"), builtinSelectorProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := *builtinSelectorProfile
+	want.Search.ResultTitle = Selectors{"h4"}
+	want.Search.ResultLink = Selectors{"a.title", "a"}
+
+	if !reflect.DeepEqual(*profile, want) {
+		t.Errorf("got %+v\nwant %+v", *profile, want)
+	}
+
+	if builtinSelectorProfile.Search.ResultTitle[0] != "h3" {
+		t.Errorf("profile changed the built-in profile")
+	}
+}
+
+func TestParseSelectorProfileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		wantErr string
+	}{
+		{name: "unsupported version", file: "version: 2\n", wantErr: "unsupported selector profile version 2"},
+		{name: "unknown field", file: "version: 1\nsearch:\n  resultTitel: h3\n", wantErr: "field resultTitel not found"},
+		{name: "invalid selector", file: "version: 1\nsearch:\n  resultTitle: 'h3['\n", wantErr: `invalid selector "h3["`},
+		{name: "empty field", file: "version: 1\nsearch:\n  resultTitle:\n", wantErr: "search.resultTitle: at least one selector expected"},
+		{name: "null field", file: "version: 1\nimages:\n  result: null\n", wantErr: "images.result: at least one selector expected"},
+		{name: "empty list", file: "version: 1\nvideos:\n  resultLink: []\n", wantErr: "at least one selector expected"},
+		{name: "blank selector", file: "version: 1\nsearch:\n  resultLink: [a, ' ']\n", wantErr: `invalid selector " "`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseSelectorProfile([]byte(test.file), builtinSelectorProfile)
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("got error %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestFallbackSelectors(t *testing.T) {
+	document, err := goquery.NewDocumentFromReader(strings.NewReader(`<div><h3 class="first">one</h3><h3>two</h3><h4>three</h4></div>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		selectors Selectors
+		wantFirst string
+		wantAll   string
+	}{
+		{name: "first selector matches", selectors: Selectors{"h3", "h4"}, wantFirst: "one", wantAll: "onetwo"},
+		{name: "second selector matches", selectors: Selectors{"h5", "h4", "h3"}, wantFirst: "three", wantAll: "three"},
+		{name: "no selector matches", selectors: Selectors{"h5", "h6"}},
+		{name: "no selectors", selectors: Selectors{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := findFirst(document.Selection, test.selectors).Text(); got != test.wantFirst {
+				t.Errorf("findFirst got %q, want %q", got, test.wantFirst)
+			}
+			if got := findAll(document.Selection, test.selectors).Text(); got != test.wantAll {
+				t.Errorf("findAll got %q, want %q", got, test.wantAll)
+			}
+		})
+	}
+}
+
+func TestParseWithFallbackSelectors(t *testing.T) {
+	document := loadFixture(t, "google/search/first-page.html")
+
+	want, err := parseSearchPage(document, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// selectors of an imagined new markup come first and don't match
+	profile, err := parseSelectorProfile([]byte("version: 1\nsearch:\n  result: [.new-result, .fP1Qef]\n  resultTitle: [h2.title, h3]\n"), builtinSelectorProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	useSelectorProfile(t, profile)
+
+	got, err := parseSearchPage(document, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.SearchResults) == 0 || !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestLoadSelectorProfile(t *testing.T) {
+	useSelectorProfile(t, builtinSelectorProfile)
+	path := filepath.Join(t.TempDir(), "selectors.yaml")
+
+	if err := os.WriteFile(path, []byte("version: 1\nsearch:\n  resultTitle: h4\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadSelectorProfile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := getSelectorProfile()
+	if !reflect.DeepEqual(loaded.Search.ResultTitle, Selectors{"h4"}) {
+		t.Fatalf("got result title selectors %v, want the profile's", loaded.Search.ResultTitle)
+	}
+
+	// a reload with an invalid file keeps the loaded profile
+	if err := os.WriteFile(path, []byte("version: 1\nsearch:\n  resultTitle:\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadSelectorProfile(path); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("got error %v, want the file to be rejected", err)
+	}
+
+	if getSelectorProfile() != loaded {
+		t.Errorf("rejected profile replaced the loaded one")
+	}
+
+	if _, err := parseSearchPage(loadFixture(t, "google/search/first-page.html"), 0); err != nil {
+		t.Errorf("parser fails after the rejected reload: %s", err)
+	}
+}
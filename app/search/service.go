@@ -1,22 +1,29 @@
 package search
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/url"
-	"strconv"
+	"time"
 
-	"github.com/PuerkitoBio/goquery"
+	"sitelook/app/metrics"
 )
 
 const (
-	SearchResponseError   = 0
-	SearchResponsePage    = 1
-	SearchResponseCaptcha = 2
+	SearchResponseError      = 0
+	SearchResponsePage       = 1
+	SearchResponseCaptcha    = 2
+	SearchResponseProxyError = 3
 )
 
 type SearchResponse struct {
 	Type       int
 	Status     int
+	Backend    string
+	Cached     bool
 	Captcha    *CaptchaPage
 	SearchPage *SearchPage
 }
@@ -24,6 +31,8 @@ type SearchResponse struct {
 type ImageSearchResponse struct {
 	Type       int
 	Status     int
+	Backend    string
+	Cached     bool
 	Captcha    *CaptchaPage
 	ImagesPage *ImagesPage
 }
@@ -31,154 +40,346 @@ type ImageSearchResponse struct {
 type VideoSearchResponse struct {
 	Type       int
 	Status     int
+	Backend    string
+	Cached     bool
 	Captcha    *CaptchaPage
 	VideosPage *VideosPage
 }
 
-func Search(searchTerm string, params SearchQueryParams) (SearchResponse, error) {
-	searchUrl := getSearchUrl(searchTerm, params.Start, "", params.SearchLanguage, params.InterfaceLanguage)
-	document, err, status := getDocument(searchUrl)
+func Search(ctx context.Context, searchTerm string, params SearchQueryParams) (SearchResponse, error) {
+	ctx = withLanguage(ctx, params.InterfaceLanguage)
+	started := time.Now()
+	response, hit, err := cached(ctx, createCacheKey(searchTerm, "", params), func() (SearchResponse, error) {
+		return searchWithFailover(ctx, searchTerm, params)
+	}, func(response SearchResponse, err error) bool {
+		return err == nil && response.Type == SearchResponsePage
+	})
+
+	response.Cached = hit
+	observeSearch("web", response.Backend, response.Type, started)
+	return response, err
+}
+
+func observeSearch(vertical string, backendName string, responseType int, started time.Time) {
+	result := metrics.ResultError
+	if responseType == SearchResponsePage {
+		result = metrics.ResultPage
+	} else if responseType == SearchResponseCaptcha {
+		result = metrics.ResultCaptcha
+	} else if responseType == SearchResponseProxyError {
+		result = metrics.ResultProxy
+	}
+
+	metrics.ObserveSearch(vertical, backendName, result, time.Since(started))
+}
 
+func searchWithFailover(ctx context.Context, searchTerm string, params SearchQueryParams) (SearchResponse, error) {
+	if isMetasearch(params.Backend) {
+		return Metasearch(ctx, searchTerm, params)
+	}
+
+	chain, err := getFailoverChain(params.Backend)
 	if err != nil {
 		return SearchResponse{Type: SearchResponseError, Status: 0}, err
 	}
 
+	response := SearchResponse{}
+
+	for _, backend := range chain {
+		response, err = searchBackend(ctx, backend, searchTerm, params)
+		if !isFailover(ctx, backend, response.Type, response.Status, err) {
+			break
+		}
+	}
+
+	return response, err
+}
+
+func searchBackend(ctx context.Context, backend Backend, searchTerm string, params SearchQueryParams) (SearchResponse, error) {
+	searchUrl, err := backend.SearchUrl(searchTerm, "", params)
+	if err != nil {
+		return SearchResponse{Type: SearchResponseError, Status: 0, Backend: backend.Name()}, err
+	}
+
+	body, err, status := backend.Fetch(ctx, searchUrl)
+
+	if err != nil {
+		responseType, err := fetchError(backend, searchUrl, err)
+		return SearchResponse{Type: responseType, Status: 0, Backend: backend.Name()}, err
+	}
+
 	if status != http.StatusOK {
 		if status == http.StatusTooManyRequests {
-			captchaPage := createCaptchaPage(searchTerm)
-			return SearchResponse{Type: SearchResponseCaptcha, Captcha: &captchaPage, Status: status}, err
+			metrics.CountCaptcha(backend.Name())
+			captchaPage := createCaptchaPage(searchTerm, backend, searchUrl)
+			return SearchResponse{Type: SearchResponseCaptcha, Captcha: &captchaPage, Status: status, Backend: backend.Name()}, newSearchError(ErrCaptcha, backend, searchUrl, status, nil)
 		}
-		return SearchResponse{Type: SearchResponseError, Status: status}, nil
+		return SearchResponse{Type: SearchResponseError, Status: status, Backend: backend.Name()}, newSearchError(ErrUpstreamStatus, backend, searchUrl, status, nil)
 	}
 
 	if len(searchTerm) == 0 {
 		searchPage := createEmptySearchPage()
-		return SearchResponse{Type: SearchResponsePage, SearchPage: &searchPage, Status: status}, nil
+		return SearchResponse{Type: SearchResponsePage, SearchPage: &searchPage, Status: status, Backend: backend.Name()}, nil
 	} else {
-		searchPage, err := parseSearchPage(document, params.Start)
+		searchPage, err := backend.ParseSearchPage(body, params)
+
+		// captcha pages served with status 200 aren't parser failures
+		if errors.Is(err, ErrCaptcha) {
+			metrics.CountCaptcha(backend.Name())
+			captchaPage := createCaptchaPage(searchTerm, backend, searchUrl)
+			return SearchResponse{Type: SearchResponseCaptcha, Captcha: &captchaPage, Status: status, Backend: backend.Name()}, newSearchError(ErrCaptcha, backend, searchUrl, status, nil)
+		}
+
+		results := 0
+		if searchPage != nil {
+			results = len(searchPage.SearchResults)
+		}
+		recordParsedPage(parserName(backend.Name(), webParser), searchUrl, body, status, results, err)
 
 		if err != nil {
-			return SearchResponse{Type: SearchResponseError, Status: status}, err
+			return SearchResponse{Type: SearchResponseError, Status: status, Backend: backend.Name()}, newSearchError(ErrParse, backend, searchUrl, status, err)
 		}
 
-		return SearchResponse{Type: SearchResponsePage, SearchPage: searchPage, Status: status}, nil
+		return SearchResponse{Type: SearchResponsePage, SearchPage: searchPage, Status: status, Backend: backend.Name()}, nil
 	}
 }
 
-func ImageSearch(searchTerm string, params SearchQueryParams) (ImageSearchResponse, error) {
-	searchUrl := getSearchUrl(searchTerm, params.Start, "isch", params.SearchLanguage, params.InterfaceLanguage)
-	document, err, status := getDocument(searchUrl)
+func ImageSearch(ctx context.Context, searchTerm string, params SearchQueryParams) (ImageSearchResponse, error) {
+	ctx = withLanguage(ctx, params.InterfaceLanguage)
+	started := time.Now()
+	response, hit, err := cached(ctx, createCacheKey(searchTerm, "isch", params), func() (ImageSearchResponse, error) {
+		return imageSearchWithFailover(ctx, searchTerm, params)
+	}, func(response ImageSearchResponse, err error) bool {
+		return err == nil && response.Type == SearchResponsePage
+	})
+
+	response.Cached = hit
+	observeSearch("images", response.Backend, response.Type, started)
+	return response, err
+}
 
+func imageSearchWithFailover(ctx context.Context, searchTerm string, params SearchQueryParams) (ImageSearchResponse, error) {
+	chain, err := getFailoverChain(params.Backend)
 	if err != nil {
 		return ImageSearchResponse{Type: SearchResponseError, Status: 0}, err
 	}
 
+	response := ImageSearchResponse{}
+
+	for _, backend := range chain {
+		response, err = imageSearchBackend(ctx, backend, searchTerm, params)
+		if !isFailover(ctx, backend, response.Type, response.Status, err) {
+			break
+		}
+	}
+
+	return response, err
+}
+
+func imageSearchBackend(ctx context.Context, backend Backend, searchTerm string, params SearchQueryParams) (ImageSearchResponse, error) {
+	searchUrl, err := backend.SearchUrl(searchTerm, "isch", params)
+	if err != nil {
+		return ImageSearchResponse{Type: SearchResponseError, Status: 0, Backend: backend.Name()}, err
+	}
+
+	body, err, status := backend.Fetch(ctx, searchUrl)
+
+	if err != nil {
+		responseType, err := fetchError(backend, searchUrl, err)
+		return ImageSearchResponse{Type: responseType, Status: 0, Backend: backend.Name()}, err
+	}
+
 	if status != http.StatusOK {
 		if status == http.StatusTooManyRequests {
-			captchaPage := createCaptchaPage(searchTerm)
-			return ImageSearchResponse{Type: SearchResponseCaptcha, Captcha: &captchaPage, Status: status}, err
+			metrics.CountCaptcha(backend.Name())
+			captchaPage := createCaptchaPage(searchTerm, backend, searchUrl)
+			return ImageSearchResponse{Type: SearchResponseCaptcha, Captcha: &captchaPage, Status: status, Backend: backend.Name()}, newSearchError(ErrCaptcha, backend, searchUrl, status, nil)
 		}
-		return ImageSearchResponse{Type: SearchResponseError, Status: status}, nil
+		return ImageSearchResponse{Type: SearchResponseError, Status: status, Backend: backend.Name()}, newSearchError(ErrUpstreamStatus, backend, searchUrl, status, nil)
 	}
 
 	if len(searchTerm) == 0 {
 		imagesPage := createEmptyImagesPage()
-		return ImageSearchResponse{Type: SearchResponsePage, ImagesPage: &imagesPage, Status: status}, nil
+		return ImageSearchResponse{Type: SearchResponsePage, ImagesPage: &imagesPage, Status: status, Backend: backend.Name()}, nil
 	} else {
-		imagesPage, err := parseImagesPage(document)
+		imagesPage, err := backend.ParseImagesPage(body, params)
+
+		if errors.Is(err, ErrCaptcha) {
+			metrics.CountCaptcha(backend.Name())
+			captchaPage := createCaptchaPage(searchTerm, backend, searchUrl)
+			return ImageSearchResponse{Type: SearchResponseCaptcha, Captcha: &captchaPage, Status: status, Backend: backend.Name()}, newSearchError(ErrCaptcha, backend, searchUrl, status, nil)
+		}
+
+		recordParsedPage(parserName(backend.Name(), imagesParser), searchUrl, body, status, len(imagesPage.ImageResults), err)
 
 		if err != nil {
-			return ImageSearchResponse{Type: SearchResponseError, ImagesPage: &imagesPage, Status: status}, err
+			return ImageSearchResponse{Type: SearchResponseError, Status: status, Backend: backend.Name()}, newSearchError(ErrParse, backend, searchUrl, status, err)
 		}
 
-		return ImageSearchResponse{Type: SearchResponsePage, ImagesPage: &imagesPage, Status: status}, nil
+		return ImageSearchResponse{Type: SearchResponsePage, ImagesPage: &imagesPage, Status: status, Backend: backend.Name()}, nil
 	}
 }
 
-func VideoSearch(searchTerm string, params SearchQueryParams) (VideoSearchResponse, error) {
-	searchUrl := getSearchUrl(searchTerm, params.Start, "vid", params.SearchLanguage, params.InterfaceLanguage)
-	document, err, status := getDocument(searchUrl)
+func VideoSearch(ctx context.Context, searchTerm string, params SearchQueryParams) (VideoSearchResponse, error) {
+	ctx = withLanguage(ctx, params.InterfaceLanguage)
+	started := time.Now()
+	response, hit, err := cached(ctx, createCacheKey(searchTerm, "vid", params), func() (VideoSearchResponse, error) {
+		return videoSearchWithFailover(ctx, searchTerm, params)
+	}, func(response VideoSearchResponse, err error) bool {
+		return err == nil && response.Type == SearchResponsePage
+	})
+
+	response.Cached = hit
+	observeSearch("videos", response.Backend, response.Type, started)
+	return response, err
+}
 
+func videoSearchWithFailover(ctx context.Context, searchTerm string, params SearchQueryParams) (VideoSearchResponse, error) {
+	chain, err := getFailoverChain(params.Backend)
 	if err != nil {
 		return VideoSearchResponse{Type: SearchResponseError, Status: 0}, err
 	}
 
+	response := VideoSearchResponse{}
+
+	for _, backend := range chain {
+		response, err = videoSearchBackend(ctx, backend, searchTerm, params)
+		if !isFailover(ctx, backend, response.Type, response.Status, err) {
+			break
+		}
+	}
+
+	return response, err
+}
+
+func videoSearchBackend(ctx context.Context, backend Backend, searchTerm string, params SearchQueryParams) (VideoSearchResponse, error) {
+	searchUrl, err := backend.SearchUrl(searchTerm, "vid", params)
+	if err != nil {
+		return VideoSearchResponse{Type: SearchResponseError, Status: 0, Backend: backend.Name()}, err
+	}
+
+	body, err, status := backend.Fetch(ctx, searchUrl)
+
+	if err != nil {
+		responseType, err := fetchError(backend, searchUrl, err)
+		return VideoSearchResponse{Type: responseType, Status: 0, Backend: backend.Name()}, err
+	}
+
 	if status != http.StatusOK {
 		if status == http.StatusTooManyRequests {
-			captchaPage := createCaptchaPage(searchTerm)
-			return VideoSearchResponse{Type: SearchResponseCaptcha, Captcha: &captchaPage, Status: status}, err
+			metrics.CountCaptcha(backend.Name())
+			captchaPage := createCaptchaPage(searchTerm, backend, searchUrl)
+			return VideoSearchResponse{Type: SearchResponseCaptcha, Captcha: &captchaPage, Status: status, Backend: backend.Name()}, newSearchError(ErrCaptcha, backend, searchUrl, status, nil)
 		}
-		return VideoSearchResponse{Type: SearchResponseError, Status: status}, nil
+		return VideoSearchResponse{Type: SearchResponseError, Status: status, Backend: backend.Name()}, newSearchError(ErrUpstreamStatus, backend, searchUrl, status, nil)
 	}
 
 	if len(searchTerm) == 0 {
 		videosPage := createEmptyVideosPage()
-		return VideoSearchResponse{Type: SearchResponsePage, VideosPage: &videosPage, Status: status}, nil
+		return VideoSearchResponse{Type: SearchResponsePage, VideosPage: &videosPage, Status: status, Backend: backend.Name()}, nil
 	} else {
-		videosPage, err := parseVideosPage(document)
+		videosPage, err := backend.ParseVideosPage(body, params)
+
+		if errors.Is(err, ErrCaptcha) {
+			metrics.CountCaptcha(backend.Name())
+			captchaPage := createCaptchaPage(searchTerm, backend, searchUrl)
+			return VideoSearchResponse{Type: SearchResponseCaptcha, Captcha: &captchaPage, Status: status, Backend: backend.Name()}, newSearchError(ErrCaptcha, backend, searchUrl, status, nil)
+		}
+
+		recordParsedPage(parserName(backend.Name(), videosParser), searchUrl, body, status, len(videosPage.VideoResults), err)
 
 		if err != nil {
-			return VideoSearchResponse{Type: SearchResponseError, VideosPage: &videosPage, Status: status}, err
+			return VideoSearchResponse{Type: SearchResponseError, Status: status, Backend: backend.Name()}, newSearchError(ErrParse, backend, searchUrl, status, err)
 		}
 
-		return VideoSearchResponse{Type: SearchResponsePage, VideosPage: &videosPage, Status: status}, nil
+		return VideoSearchResponse{Type: SearchResponsePage, VideosPage: &videosPage, Status: status, Backend: backend.Name()}, nil
 	}
 }
 
-func getSearchUrl(searchTerm string, start int, searchType string, searchLang string, interfaceLang string) string {
-	searchUrl, _ := url.Parse("https://google.com/search")
-	query := searchUrl.Query()
+// getDocument serves successful responses from the disk cache when it is
+// enabled and stores new ones in it. In the replay mode responses are only
+// read from recordings. header holds the backend's own headers, they're sent
+// with the browser fingerprint.
+func getDocument(ctx context.Context, backendName string, url string, header http.Header) (body []byte, err error, status int) {
+	if len(replayDir) > 0 {
+		return replayResponse(url)
+	}
 
-	query.Add("q", searchTerm)
+	cache := responseCache
 
-	if start > 0 {
-		query.Add("start", strconv.Itoa(start))
+	if cache != nil {
+		if body, status, ok := cache.get(url); ok {
+			// recordings hold every page searched while recording, including
+			// the ones served from the disk cache
+			if len(recordDir) > 0 {
+				recordResponse(url, body, status)
+			}
+			return body, nil, status
+		}
 	}
 
-	if len(searchType) > 0 {
-		query.Add("tbm", searchType)
-	}
+	started := time.Now()
+	body, err, status = fetchDocument(ctx, url, header)
+	metrics.ObserveUpstream(backendName, status, time.Since(started))
 
-	if len(searchLang) > 0 {
-		query.Add("lr", searchLang)
+	if len(recordDir) > 0 && err == nil {
+		recordResponse(url, body, status)
 	}
 
-	if len(interfaceLang) > 0 {
-		query.Add("hl", interfaceLang)
+	if cache != nil && err == nil && status == http.StatusOK {
+		cache.put(url, body, status)
 	}
 
-	// non-js version
-	query.Set("gbv", "1")
+	return body, err, status
+}
+
+func fetchDocument(ctx context.Context, documentUrl string, header http.Header) (body []byte, err error, status int) {
+	ctx, cancel := context.WithTimeout(ctx, upstreamTimeout)
+	defer cancel()
 
-	searchUrl.RawQuery = query.Encode()
-	return searchUrl.String()
+	if pool := proxies; pool != nil {
+		return fetchThroughProxies(ctx, pool, documentUrl, header)
+	}
+
+	return fetchWithClient(ctx, upstreamClient, documentUrl, browserHeader(ctx, "", header))
 }
 
-func getDocument(url string) (document *goquery.Document, err error, status int) {
-	client := http.Client{}
-	req, err := http.NewRequest("GET", url, nil)
+func fetchWithClient(ctx context.Context, client *http.Client, documentUrl string, header http.Header) (body []byte, err error, status int) {
+	req, err := http.NewRequestWithContext(ctx, "GET", documentUrl, nil)
 
 	if err != nil {
-		return nil, err, 0
+		return nil, withoutUrl(err), 0
 	}
 
-	req.Header = http.Header{
-		"Accept":          {"text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8"},
-		"Accept-Language": {"en-US,en;q=0.8"},
-		"User-Agent":      {"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/114.0.0.0 Safari/537.36"},
-	}
+	req.Header = header
 
 	res, err := client.Do(req)
 	if err != nil {
-		return nil, err, 0
+		return nil, withoutUrl(err), 0
 	}
 
 	defer res.Body.Close()
 
-	doc, err := goquery.NewDocumentFromReader(res.Body)
+	// one byte over the limit tells a response of exactly the maximum size
+	// apart from a larger one
+	body, err = io.ReadAll(io.LimitReader(res.Body, maxResponseSize+1))
 	if err != nil {
 		return nil, err, res.StatusCode
 	}
 
-	return doc, nil, res.StatusCode
+	if int64(len(body)) > maxResponseSize {
+		return nil, fmt.Errorf("response is larger than %d bytes", maxResponseSize), res.StatusCode
+	}
+
+	return body, nil, res.StatusCode
+}
+
+// withoutUrl strips the url from request errors since it contains the search
+// term, which is kept out of the logs
+func withoutUrl(err error) error {
+	var urlError *url.Error
+	if errors.As(err, &urlError) {
+		return urlError.Err
+	}
+	return err
 }
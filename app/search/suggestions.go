@@ -0,0 +1,112 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// SuggestionBackend is implemented by backends which can complete search
+// terms. Suggestion responses are expected in the OpenSearch suggestions
+// format: `["search term", ["suggestion 1", "suggestion 2"]]`.
+type SuggestionBackend interface {
+	SuggestionsUrl(searchTerm string, params SearchQueryParams) string
+}
+
+var ErrSuggestionsNotSupported = errors.New("backend doesn't provide search suggestions")
+
+// Suggestions aren't fetched from backends skipped after captchas or errors,
+// nor in the replay mode which never sends requests upstream
+var ErrSuggestionsSkipped = errors.New("suggestions skipped")
+
+// Completions of the search term rendered in the search input's datalist
+var completionsEnabled = false
+var completionsTimeout = time.Second
+
+func SetCompletionsEnabled(enabled bool) {
+	completionsEnabled = enabled
+}
+
+// Suggest fetches completions of the search term from the selected backend,
+// so the user's keystrokes are never sent to it directly. They are fetched
+// past the disk cache, the recorder and the upstream metrics, which would
+// keep every typed prefix.
+func Suggest(ctx context.Context, searchTerm string, params SearchQueryParams) ([]string, error) {
+	ctx = withLanguage(ctx, params.InterfaceLanguage)
+
+	backend, err := getBackend(params.Backend)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestionBackend, ok := backend.(SuggestionBackend)
+	if !ok {
+		return nil, ErrSuggestionsNotSupported
+	}
+
+	if len(replayDir) > 0 || !getBreaker(backend.Name()).allow() {
+		return nil, ErrSuggestionsSkipped
+	}
+
+	header := http.Header{"Accept": {"application/json"}}
+	body, err, status := fetchDocument(ctx, suggestionBackend.SuggestionsUrl(searchTerm, params), header)
+	if err != nil {
+		return nil, err
+	}
+
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("suggestions request failed with status %d", status)
+	}
+
+	return parseOpenSearchSuggestions(body)
+}
+
+func parseOpenSearchSuggestions(body []byte) ([]string, error) {
+	response := []json.RawMessage{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+
+	if len(response) < 2 {
+		return nil, errors.New("suggestions response has no suggestion list")
+	}
+
+	suggestions := []string{}
+	if err := json.Unmarshal(response[1], &suggestions); err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
+
+// startCompletions fetches suggestions for the search input while the search
+// is running. The returned function waits for them until the timeout and
+// renders the page without suggestions when they take too long.
+func startCompletions(ctx context.Context, searchTerm string, params SearchQueryParams) func() []string {
+	if !completionsEnabled {
+		return func() []string { return nil }
+	}
+
+	completions := make(chan []string, 1)
+
+	go func() {
+		suggestions, err := Suggest(ctx, searchTerm, params)
+		if err != nil && !errors.Is(err, ErrSuggestionsNotSupported) && !errors.Is(err, ErrSuggestionsSkipped) {
+			slog.Warn("suggestions failed", "error", err)
+		}
+		completions <- suggestions
+	}()
+
+	return func() []string {
+		select {
+		case suggestions := <-completions:
+			return suggestions
+		case <-time.After(completionsTimeout):
+			return nil
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseOpenSearchSuggestions(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "suggestions", body: `["golang", ["golang tutorial", "golang vs rust"]]`, want: []string{"golang tutorial", "golang vs rust"}},
+		{name: "descriptions and urls", body: `["golang", ["golang tutorial"], ["Learn Go"], ["https://go.dev"]]`, want: []string{"golang tutorial"}},
+		{name: "no suggestions", body: `["qwxzvbnmlkjhgf", []]`, want: []string{}},
+		{name: "no suggestion list", body: `["golang"]`, wantErr: true},
+		{name: "suggestions aren't strings", body: `["golang", [{"phrase": "golang tutorial"}]]`, wantErr: true},
+		{name: "not json", body: `<html></html>`, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseOpenSearchSuggestions([]byte(test.body))
+
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("got %v, want an error", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+// serveSuggestions registers a searxng backend whose autocompleter answers
+// every request with the same suggestions and counts the requests
+func serveSuggestions(t *testing.T) (requests func() int64) {
+	t.Helper()
+
+	var count atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count.Add(1)
+		w.Write([]byte(`["gol", ["golang", "golf"]]`))
+	}))
+	t.Cleanup(server.Close)
+
+	backend, err := NewSearxngBackend(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous, registered := backends[backend.Name()]
+	RegisterBackend(backend)
+	t.Cleanup(func() {
+		if registered {
+			backends[backend.Name()] = previous
+		} else {
+			delete(backends, backend.Name())
+		}
+	})
+
+	resetBreakers(t, 3, time.Minute)
+
+	return count.Load
+}
+
+func TestSuggestSkipsDiskCacheAndRecorder(t *testing.T) {
+	serveSuggestions(t)
+
+	cacheDir, recordingsDir := t.TempDir(), t.TempDir()
+
+	if err := SetDiskCache(cacheDir, 1<<20, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetDiskCache("", 0, 0) })
+
+	if err := SetRecordDir(recordingsDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetRecordDir("") })
+
+	suggestions, err := Suggest(context.Background(), "gol", SearchQueryParams{Backend: SearxngBackendName})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"golang", "golf"}; !reflect.DeepEqual(suggestions, want) {
+		t.Errorf("got %v, want %v", suggestions, want)
+	}
+
+	for _, dir := range []string{cacheDir, recordingsDir} {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) > 0 {
+			t.Errorf("suggestions were written to %s: %v", dir, entries)
+		}
+	}
+}
+
+func TestSuggestRouteSkipsOpenBreaker(t *testing.T) {
+	requests := serveSuggestions(t)
+	getBreaker(SearxngBackendName).recordFailure(true)
+
+	if _, err := Suggest(context.Background(), "gol", SearchQueryParams{Backend: SearxngBackendName}); !errors.Is(err, ErrSuggestionsSkipped) {
+		t.Errorf("got error %v, want suggestions to be skipped", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/suggest", SuggestRoute)
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/suggest?q=gol&backend="+SearxngBackendName, nil))
+
+	if got, want := recorder.Body.String(), `["gol",[]]`; recorder.Code != http.StatusOK || got != want {
+		t.Errorf("got status %d and %s, want %s", recorder.Code, got, want)
+	}
+
+	if got := requests(); got != 0 {
+		t.Errorf("skipped backend got %d suggestion requests", got)
+	}
+}
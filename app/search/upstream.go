@@ -0,0 +1,35 @@
+package search
+
+import (
+	"net/http"
+	"time"
+)
+
+// Each request to a backend gets this deadline on top of the caller's context
+var upstreamTimeout = 10 * time.Second
+
+// Larger responses are cut off with an error, Google's pages are well below
+var maxResponseSize int64 = 8 << 20
+
+// upstreamClient is shared by all direct backend requests, so connections to
+// the backends are reused
+var upstreamClient = &http.Client{Transport: newUpstreamTransport()}
+
+// newUpstreamTransport creates a transport respecting the HTTP_PROXY variables
+// like the default one
+func newUpstreamTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = 100
+	transport.MaxIdleConnsPerHost = 16
+	transport.IdleConnTimeout = 90 * time.Second
+	transport.TLSHandshakeTimeout = 5 * time.Second
+	return transport
+}
+
+func SetUpstreamTimeout(timeout time.Duration) {
+	upstreamTimeout = timeout
+}
+
+func SetMaxResponseSize(size int64) {
+	maxResponseSize = size
+}
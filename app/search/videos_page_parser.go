@@ -2,7 +2,7 @@ package search
 
 import (
 	"errors"
-	"log"
+	"log/slog"
 
 	"github.com/PuerkitoBio/goquery"
 )
@@ -22,17 +22,24 @@ type VideosPage struct {
 }
 
 func parseVideosPage(document *goquery.Document) (VideosPage, error) {
+	if isCaptchaPage(document) {
+		return VideosPage{}, ErrCaptcha
+	}
+
+	selectors := getSelectorProfile().Videos
+
 	// TODO: extract in a separate function
-	searchInput := findSingle(document.Selection, "input[name=\"q\"]")
+	searchInput := findFirst(document.Selection, selectors.SearchInput)
 	if selectionEmpty(searchInput) {
+		recordMissingField(googleVideosParser, "search input")
 		return VideosPage{}, errors.New("search input not found")
 	}
 	searchTerm := searchInput.AttrOr("value", "")
 
 	videoResults := make([]VideoResult, 0)
 
-	document.Find("#main > div > div").Each(func(i int, item *goquery.Selection) {
-		h3 := findSingle(item, "h3")
+	findAll(document.Selection, selectors.Result).Each(func(i int, item *goquery.Selection) {
+		h3 := findFirst(item, selectors.ResultTitle)
 
 		// Search filters div or `Related searches` div
 		if selectionEmpty(h3) {
@@ -41,15 +48,27 @@ func parseVideosPage(document *goquery.Document) (VideosPage, error) {
 
 		title := h3.Text()
 
-		a := findSingle(item, "a")
+		a := findFirst(item, selectors.ResultLink)
 		videoHref := hrefFromQuery(a.AttrOr("href", "#"))
 
-		img := findSingle(item, "img")
+		if selectionEmpty(a) {
+			recordMissingField(googleVideosParser, "result link")
+		}
+
+		img := findFirst(item, selectors.ResultImage)
 		imgSrc := img.AttrOr("src", "")
 
-		descriptionDiv := findSingle(item, "div>span").Parent()
+		if len(imgSrc) == 0 {
+			recordMissingField(googleVideosParser, "result image")
+		}
+
+		descriptionDiv := findFirst(item, selectors.ResultDescription).Parent()
 		descriptionHtml := descriptionDiv.Text()
 
+		if selectionEmpty(descriptionDiv) {
+			recordMissingField(googleVideosParser, "result description")
+		}
+
 		videoResults = append(videoResults, VideoResult{
 			Title:         title,
 			UrlTitle:      videoHref, // FIXME: prettify
@@ -59,17 +78,19 @@ func parseVideosPage(document *goquery.Document) (VideosPage, error) {
 		})
 	})
 
+	// a search which found nothing, see parseImagesPage
 	if len(videoResults) == 0 {
 		return VideosPage{
 			SearchTerm:   searchTerm,
 			VideoResults: videoResults,
 			Pagination:   SinglePagePagination{},
-		}, errors.New("page has no images or an error occured while parsing images")
+		}, nil
 	}
 
-	pagination, err := parseVideoPagePagination(document)
+	pagination, err := parseVideoPagePagination(document, selectors)
 	if err != nil {
-		log.Println(err)
+		slog.Debug("pagination error", "parser", googleVideosParser, "error", err)
+		recordPaginationError(googleVideosParser, err)
 	}
 
 	return VideosPage{
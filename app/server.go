@@ -1,20 +1,214 @@
 package app
 
 import (
+	"context"
+	"io/fs"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"sitelook/app/admin"
+	"sitelook/app/assets"
+	"sitelook/app/config"
 	"sitelook/app/home"
+	"sitelook/app/imgproxy"
+	"sitelook/app/logging"
+	"sitelook/app/metrics"
+	"sitelook/app/opensearch"
 	"sitelook/app/search"
 
 	"github.com/gin-gonic/gin"
 )
 
-func RunServer() {
-	engine := gin.Default()
+func RunServer(config config.Config, files fs.FS) {
+	logLevel := config.LogLevel
+	if config.Debug {
+		logLevel = "debug"
+	}
+
+	if err := logging.Setup(logLevel, config.LogFormat); err != nil {
+		log.Fatal(err)
+	}
+
+	if len(config.IgnoredVariables) > 0 {
+		slog.Warn("environment variables not matching any option are ignored", "variables", config.IgnoredVariables)
+	}
+
+	// contexts of requests are derived from it, it's cancelled when requests
+	// don't finish in time on shutdown, which cancels their backend requests
+	requestsContext, cancelRequests := context.WithCancel(context.Background())
+
+	logging.SetQueryLogging(config.LogQueries)
+	logging.SetPageDumps(config.Debug)
+
+	opensearch.SetBaseUrl(config.BaseUrl)
+	imgproxy.SetEnabled(config.ImageProxy)
+	imgproxy.SetUserAgent(config.UserAgent)
+
+	if len(config.ImageProxyKey) > 0 {
+		imgproxy.SetKey([]byte(config.ImageProxyKey))
+	}
+
+	if len(config.SearxngUrl) > 0 {
+		searxngBackend, err := search.NewSearxngBackend(config.SearxngUrl)
+		if err != nil {
+			fatal(err)
+		}
+		search.RegisterBackend(searxngBackend)
+	}
+
+	if err := search.SetMetasearchBackends(config.MetasearchBackends); err != nil {
+		fatal(err)
+	}
+
+	search.SetMetasearchTimeout(config.MetasearchTimeout)
+
+	if err := search.SetFallbackBackends(config.FallbackBackends); err != nil {
+		fatal(err)
+	}
+
+	search.SetCircuitBreaker(config.BreakerThreshold, config.BreakerCooldown)
+	search.SetUpstreamTimeout(config.UpstreamTimeout)
+	search.SetMaxResponseSize(config.MaxResponseSize)
+
+	if len(config.Fingerprints) > 0 {
+		if err := search.LoadFingerprints(config.Fingerprints); err != nil {
+			fatal(err)
+		}
+	}
+
+	if err := search.SetFingerprintRotation(config.FingerprintRotation, config.FingerprintSession); err != nil {
+		fatal(err)
+	}
+
+	if err := search.SetProxies(config.Proxies, config.ProxyRotation, config.ProxyIsolation); err != nil {
+		fatal(err)
+	}
+
+	// images are fetched through the same proxies as searches, so thumbnails
+	// don't reveal the instance's address either
+	imgproxy.SetProxyTransport(search.ProxyTransport())
+
+	if err := search.SetGoogleUrl(config.GoogleUrl); err != nil {
+		fatal(err)
+	}
+
+	search.SetCache(config.CacheSize, config.CacheTtl)
+
+	if err := search.SetDiskCache(config.DiskCacheDir, config.DiskCacheSize, config.DiskCacheTtl); err != nil {
+		fatal(err)
+	}
+
+	if err := search.SetRecordDir(config.RecordDir); err != nil {
+		fatal(err)
+	}
+
+	if err := search.SetReplayDir(config.ReplayDir); err != nil {
+		fatal(err)
+	}
+
+	if len(config.SelectorProfile) > 0 {
+		if err := search.LoadSelectorProfile(config.SelectorProfile); err != nil {
+			fatal(err)
+		}
+	}
+
+	if len(config.SelectorProfile) > 0 || len(config.Fingerprints) > 0 {
+		go reloadProfilesOnHangup(config.SelectorProfile, config.Fingerprints)
+	}
+
+	if err := search.SetBreakageDetector(config.BreakageThreshold, config.BreakageWindow); err != nil {
+		fatal(err)
+	}
+
+	if err := search.SetParserDumpDir(config.ParserDumpDir); err != nil {
+		fatal(err)
+	}
+
+	search.SetCompletionsEnabled(config.Completions)
+	search.SetDefaultLanguage(config.Language)
+
+	if err := search.SetDefaultBackend(config.DefaultBackend); err != nil {
+		fatal(err)
+	}
+
+	if err := assets.Load(files, config.ThemeDir); err != nil {
+		fatal(err)
+	}
+
+	engine := gin.New()
+	// registered before the middleware, so probes aren't logged
+	engine.GET("/readyz", readyRoute)
+	engine.Use(logging.Middleware(), gin.Recovery())
 
 	engine.GET("/", home.HomeRoute)
-	// engine.GET("/api/search", apiSearchRoute)
 	engine.GET("/search", search.SearchRoute)
+	engine.GET("/api/search", search.ApiSearchRoute)
+	engine.GET("/api/images", search.ApiImagesRoute)
+	engine.GET("/api/videos", search.ApiVideosRoute)
+	engine.GET("/suggest", search.SuggestRoute)
+	engine.GET("/opensearch.xml", opensearch.DescriptionRoute)
+	engine.GET(imgproxy.RoutePath, imgproxy.ProxyRoute)
+
+	// admin routes are served by the main listener unless a separate address
+	// is configured for them
+	adminEngine := engine
+	if len(config.AdminAddr) > 0 {
+		adminEngine = gin.New()
+		adminEngine.Use(logging.Middleware(), gin.Recovery())
+		adminEngine.SetHTMLTemplate(assets.Templates())
+	}
+
+	if config.Admin {
+		adminEngine.GET("/admin/status", admin.StatusRoute)
+		adminEngine.GET("/admin/vars", admin.VarsRoute)
+	}
+
+	if config.Metrics {
+		adminEngine.GET("/metrics", metrics.Route)
+	}
+
+	engine.GET(assets.RoutePath+"/*filepath", assets.StaticRoute)
+	engine.SetHTMLTemplate(assets.Templates())
+
+	servers := []*http.Server{newHttpServer(config.Listen, engine, config, requestsContext)}
+	if adminEngine != engine {
+		servers = append(servers, newHttpServer(config.AdminAddr, adminEngine, config, requestsContext))
+	}
+
+	serve(servers, config, cancelRequests)
+}
+
+// The selector profile and the fingerprints are reloaded on SIGHUP
+// (`kill -HUP <pid>`), so updated files are applied without a restart
+func reloadProfilesOnHangup(selectorProfile string, fingerprints string) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	for range hangup {
+		if len(selectorProfile) > 0 {
+			if err := search.LoadSelectorProfile(selectorProfile); err != nil {
+				slog.Error("selector profile reload failed", "error", err)
+			} else {
+				slog.Info("selector profile reloaded", "path", selectorProfile)
+			}
+		}
+
+		if len(fingerprints) > 0 {
+			if err := search.LoadFingerprints(fingerprints); err != nil {
+				slog.Error("fingerprints reload failed", "error", err)
+			} else {
+				slog.Info("fingerprints reloaded", "path", fingerprints)
+			}
+		}
+	}
+}
 
-	engine.Static("./static", "./static/")
-	engine.LoadHTMLGlob("templates/*")
-	engine.Run()
+// fatal logs an error in the configuration and exits
+func fatal(err error) {
+	slog.Error("server couldn't be started", "error", err)
+	os.Exit(1)
 }
@@ -1,7 +1,30 @@
 package main
 
-import "sitelook/app"
+import (
+	"embed"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"sitelook/app"
+	"sitelook/app/config"
+)
+
+// Templates and static files are built into the binary, so it runs from any
+// working directory
+//
+//go:embed templates static
+var files embed.FS
 
 func main() {
-	app.RunServer()
+	serverConfig, err := config.Load(os.Args[0], os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%s\n", err)
+		os.Exit(2)
+	}
+
+	app.RunServer(serverConfig, files)
 }